@@ -9,7 +9,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -35,6 +37,86 @@ type Config struct {
 	// BaseURL is the base URL prefix for all API endpoints.
 	// Defaults to "/api" if not specified.
 	BaseURL string
+
+	// AppRoleEnabled controls whether the AppRole authentication endpoints
+	// (/auth/approle/login and the admin AppRole CRUD routes) are mounted.
+	// Defaults to false; operators must opt in via ORBITKEYS_APPROLE_ENABLED.
+	AppRoleEnabled bool
+
+	// AppRoleDefaultSecretIDTTL is applied to SecretIDs created without an
+	// explicit secret_id_ttl. Defaults to 24 hours.
+	AppRoleDefaultSecretIDTTL time.Duration
+
+	// AppRoleDefaultTokenTTL is applied to tokens minted by an AppRole login
+	// when the AppRole has no explicit token_ttl. Defaults to 1 hour.
+	AppRoleDefaultTokenTTL time.Duration
+
+	// ConnectorsFile points at a YAML/JSON file describing the enabled
+	// identity-provider connectors (OIDC, static-password, ...), their
+	// credentials, and their groups-to-role mapping. Empty disables the
+	// connectors subsystem entirely.
+	ConnectorsFile string
+
+	// RedisURL points at a Redis instance used to track refresh-token
+	// revocation for JWT session tokens. Empty disables the JWT session
+	// token subsystem (/auth/token, JWTAuth) entirely.
+	RedisURL string
+
+	// JWTPrivateKeyPath and JWTPublicKeyPath point at a PEM-encoded RSA key
+	// pair used to sign and verify session JWTs. Both must be set for the
+	// JWT subsystem to be usable.
+	JWTPrivateKeyPath string
+	JWTPublicKeyPath  string
+
+	// JWTAccessTokenTTL and JWTRefreshTokenTTL control how long issued
+	// access and refresh tokens remain valid. Default to 15 minutes and
+	// 7 days respectively.
+	JWTAccessTokenTTL  time.Duration
+	JWTRefreshTokenTTL time.Duration
+
+	// RateLimitWindow and RateLimitMax configure CreateRateLimiter's default
+	// request budget per IP. LockoutThreshold is how many failed
+	// authentication attempts within the window middleware.ConfigureBruteForceTracking's
+	// tracker allows before locking an IP out of APIKeyAuth/RootAPIKeyAuth.
+	RateLimitWindow  time.Duration
+	RateLimitMax     int
+	LockoutThreshold int
+
+	// AuditFile, if set, additionally appends every audit event as a line
+	// of JSON to this path. The SQLite AuditLog table is always written to.
+	AuditFile string
+
+	// AuditWebhookURL, if set, additionally POSTs every audit event to this
+	// URL so an external SIEM can ingest them.
+	AuditWebhookURL string
+
+	// KeyHashAlgorithm selects how API keys are hashed at rest: "sha256"
+	// (default), "argon2id", or "bcrypt".
+	KeyHashAlgorithm string
+
+	// KeyPepper is a per-install secret mixed into every API key hash, so
+	// a stolen database dump can't be rehashed or cracked against a
+	// different deployment's key space. Generated and persisted via
+	// SaveConfig if not explicitly provided.
+	KeyPepper string
+
+	// APIKeyJWTSigningKey is the HMAC secret used to sign and verify
+	// FormatJWT API keys (see utils.CreateAPIKey). Required only if a
+	// FormatJWT key is ever created; leaving it empty disables that format.
+	APIKeyJWTSigningKey string
+
+	// KeyGenerator selects the entropy source new API keys are generated
+	// from: "crypto-rand" (default), "fips" (reads /dev/urandom directly,
+	// with a startup health check), or "kms" (delegates to a
+	// utils.KMSRandomSource wired in by the embedding application). See
+	// utils.SelectKeyGenerator.
+	KeyGenerator string
+
+	// KeyEncoding selects how generated key bytes are rendered as text:
+	// "base64url" (default) or "base32-crockford", which avoids characters
+	// that are easily confused (I/L/O/U) for keys operators need to read
+	// aloud or type by hand.
+	KeyEncoding string
 }
 
 // LoadConfig reads configuration from environment variables and .env file.
@@ -87,9 +169,149 @@ func LoadConfig() (*Config, error) {
 		config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
 	}
 
+	// AppRole settings default to disabled with conservative TTLs; operators
+	// opt in explicitly since minting tokens from a secret_id widens the
+	// system's attack surface.
+	config.AppRoleEnabled, _ = strconv.ParseBool(sanitizeEnv("ORBITKEYS_APPROLE_ENABLED"))
+
+	config.AppRoleDefaultSecretIDTTL = 24 * time.Hour
+	if ttl := sanitizeEnv("ORBITKEYS_APPROLE_SECRET_ID_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			config.AppRoleDefaultSecretIDTTL = parsed
+		} else {
+			log.Printf("Warning: invalid ORBITKEYS_APPROLE_SECRET_ID_TTL %q, using default", ttl)
+		}
+	}
+
+	config.AppRoleDefaultTokenTTL = 1 * time.Hour
+	if ttl := sanitizeEnv("ORBITKEYS_APPROLE_TOKEN_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			config.AppRoleDefaultTokenTTL = parsed
+		} else {
+			log.Printf("Warning: invalid ORBITKEYS_APPROLE_TOKEN_TTL %q, using default", ttl)
+		}
+	}
+
+	config.ConnectorsFile = sanitizeEnv("ORBITKEYS_CONNECTORS_FILE")
+	if config.ConnectorsFile != "" && !isValidFilePath(config.ConnectorsFile) {
+		return config, ErrInvalidFilePath
+	}
+
+	config.RedisURL = sanitizeEnv("ORBITKEYS_REDIS_URL")
+
+	config.JWTPrivateKeyPath = sanitizeEnv("ORBITKEYS_JWT_PRIVATE_KEY_PATH")
+	if config.JWTPrivateKeyPath != "" && !isValidFilePath(config.JWTPrivateKeyPath) {
+		return config, ErrInvalidFilePath
+	}
+
+	config.JWTPublicKeyPath = sanitizeEnv("ORBITKEYS_JWT_PUBLIC_KEY_PATH")
+	if config.JWTPublicKeyPath != "" && !isValidFilePath(config.JWTPublicKeyPath) {
+		return config, ErrInvalidFilePath
+	}
+
+	config.JWTAccessTokenTTL = 15 * time.Minute
+	if ttl := sanitizeEnv("ORBITKEYS_JWT_ACCESS_TOKEN_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			config.JWTAccessTokenTTL = parsed
+		} else {
+			log.Printf("Warning: invalid ORBITKEYS_JWT_ACCESS_TOKEN_TTL %q, using default", ttl)
+		}
+	}
+
+	config.JWTRefreshTokenTTL = 7 * 24 * time.Hour
+	if ttl := sanitizeEnv("ORBITKEYS_JWT_REFRESH_TOKEN_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			config.JWTRefreshTokenTTL = parsed
+		} else {
+			log.Printf("Warning: invalid ORBITKEYS_JWT_REFRESH_TOKEN_TTL %q, using default", ttl)
+		}
+	}
+
+	config.RateLimitWindow = 1 * time.Minute
+	if window := sanitizeEnv("ORBITKEYS_RATE_LIMIT_WINDOW"); window != "" {
+		if parsed, err := time.ParseDuration(window); err == nil {
+			config.RateLimitWindow = parsed
+		} else {
+			log.Printf("Warning: invalid ORBITKEYS_RATE_LIMIT_WINDOW %q, using default", window)
+		}
+	}
+
+	config.RateLimitMax = 100
+	if max := sanitizeEnv("ORBITKEYS_RATE_LIMIT_MAX"); max != "" {
+		if parsed, err := strconv.Atoi(max); err == nil {
+			config.RateLimitMax = parsed
+		} else {
+			log.Printf("Warning: invalid ORBITKEYS_RATE_LIMIT_MAX %q, using default", max)
+		}
+	}
+
+	config.LockoutThreshold = 10
+	if threshold := sanitizeEnv("ORBITKEYS_LOCKOUT_THRESHOLD"); threshold != "" {
+		if parsed, err := strconv.Atoi(threshold); err == nil {
+			config.LockoutThreshold = parsed
+		} else {
+			log.Printf("Warning: invalid ORBITKEYS_LOCKOUT_THRESHOLD %q, using default", threshold)
+		}
+	}
+
+	config.AuditFile = sanitizeEnv("ORBITKEYS_AUDIT_FILE")
+	if config.AuditFile != "" && !isValidFilePath(config.AuditFile) {
+		return config, ErrInvalidFilePath
+	}
+
+	config.AuditWebhookURL = sanitizeEnv("ORBITKEYS_AUDIT_WEBHOOK_URL")
+
+	config.KeyHashAlgorithm = sanitizeEnv("ORBITKEYS_KEY_HASH_ALGORITHM")
+	switch config.KeyHashAlgorithm {
+	case "":
+		config.KeyHashAlgorithm = "sha256"
+	case "sha256", "argon2id", "bcrypt":
+		// Valid as given.
+	default:
+		log.Printf("Warning: invalid ORBITKEYS_KEY_HASH_ALGORITHM %q, using sha256", config.KeyHashAlgorithm)
+		config.KeyHashAlgorithm = "sha256"
+	}
+
+	config.KeyPepper = sanitizeEnv("ORBITKEYS_KEY_PEPPER")
+
+	config.APIKeyJWTSigningKey = sanitizeEnv("ORBITKEYS_APIKEY_JWT_SIGNING_KEY")
+
+	config.KeyGenerator = sanitizeEnv("ORBITKEYS_KEY_GENERATOR")
+	switch config.KeyGenerator {
+	case "", "crypto-rand", "fips", "kms":
+		// Valid as given; utils.SelectKeyGenerator does the actual work and
+		// surfaces any runtime failure (e.g. an unreadable /dev/urandom).
+	default:
+		log.Printf("Warning: invalid ORBITKEYS_KEY_GENERATOR %q, using crypto-rand", config.KeyGenerator)
+		config.KeyGenerator = "crypto-rand"
+	}
+
+	config.KeyEncoding = sanitizeEnv("ORBITKEYS_KEY_ENCODING")
+	switch config.KeyEncoding {
+	case "", "base64url", "base32-crockford":
+		// Valid as given.
+	default:
+		log.Printf("Warning: invalid ORBITKEYS_KEY_ENCODING %q, using base64url", config.KeyEncoding)
+		config.KeyEncoding = "base64url"
+	}
+
 	return config, nil
 }
 
+// JWTEnabled reports whether enough configuration is present to mount the
+// JWT session token subsystem: a Redis URL for revocation tracking plus
+// both halves of the signing key pair.
+func (c *Config) JWTEnabled() bool {
+	return c.RedisURL != "" && c.JWTPrivateKeyPath != "" && c.JWTPublicKeyPath != ""
+}
+
+// IsValidFilePath exposes the package's file path validation for other
+// subsystems (such as the connectors loader) that accept a file path from
+// configuration and need the same directory-traversal guarantees.
+func IsValidFilePath(path string) bool {
+	return isValidFilePath(path)
+}
+
 // SaveConfig writes the configuration to a .env file.
 // This is useful for persisting generated values like the root API key.
 //
@@ -109,6 +331,9 @@ func SaveConfig(config *Config) error {
 	if config.BaseURL != "" {
 		envContent += "ORBITKEYS_BASE_URL=" + config.BaseURL + "\n"
 	}
+	if config.KeyPepper != "" {
+		envContent += "ORBITKEYS_KEY_PEPPER=" + config.KeyPepper + "\n"
+	}
 
 	// Create a temporary file first, then rename it to avoid partial writes
 	tempFile := ".env.tmp"
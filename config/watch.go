@@ -0,0 +1,108 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// current holds the most recently loaded, validated Config. RootAPIKeyAuth
+// reads through here instead of a captured cfg closure, so Watch can swap in
+// a rotated RootAPIKey (or any other setting) without a restart. It starts
+// nil; callers that never use Watch/SetCurrent are unaffected, since
+// RootAPIKeyAuth falls back to the cfg it was constructed with.
+var current atomic.Pointer[Config]
+
+// SetCurrent installs cfg as the configuration RootAPIKeyAuth (and any other
+// consumer of Current) reads. Watch calls this after every successful
+// reload; callers that want hot-reload without going through Watch (e.g. to
+// seed the initial value) may call it directly.
+func SetCurrent(cfg *Config) {
+	current.Store(cfg)
+}
+
+// Current returns the most recently installed Config, or nil if SetCurrent
+// has never been called.
+func Current() *Config {
+	return current.Load()
+}
+
+// Watch watches the .env file for writes and listens for SIGHUP, reloading
+// the configuration on either and invoking onChange with the new, validated
+// Config. Invalid reloads (LoadConfig error or ValidateConfig failure) are
+// logged and skipped, leaving the previous configuration in place.
+//
+// onChange is responsible for reacting to whatever changed: swapping in a
+// rotated RootAPIKey happens automatically via SetCurrent, but adjusting
+// BaseURL route mounts or reopening the GORM connection for a new DBPath are
+// the caller's responsibility since they touch state Watch doesn't own.
+//
+// Watch blocks until ctx is cancelled, at which point it stops the watcher
+// and signal notification and returns.
+func Watch(ctx context.Context, onChange func(cfg *Config)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// fsnotify watches directories, not individual files, since editors
+	// commonly replace a file (write-then-rename) rather than writing to it
+	// in place, which a direct file watch would miss.
+	dir := "."
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	reload := func() {
+		cfg, err := LoadConfig()
+		if err != nil {
+			log.Printf("Warning: failed to reload configuration: %v", err)
+			return
+		}
+		if !ValidateConfig(cfg) {
+			log.Println("Warning: reloaded configuration failed validation, keeping previous configuration")
+			return
+		}
+
+		SetCurrent(cfg)
+		onChange(cfg)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sighup:
+			log.Println("Received SIGHUP, reloading configuration")
+			reload()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Base(event.Name) != ".env" {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			log.Println("Detected .env change, reloading configuration")
+			reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Warning: configuration watcher error: %v", err)
+		}
+	}
+}
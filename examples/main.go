@@ -9,7 +9,6 @@ import (
 	"time"
 
 	orbitkeys "github.com/BasementPilot/orbit-keys"
-	"github.com/BasementPilot/orbit-keys/config"
 	"github.com/BasementPilot/orbit-keys/internal/middleware"
 	"github.com/BasementPilot/orbit-keys/internal/models"
 	"github.com/gofiber/fiber/v2"
@@ -22,25 +21,15 @@ func main() {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
-
-	// Initialize OrbitKeys with config
-	ok, err := orbitkeys.New(cfg)
+	// Initialize OrbitKeys; it loads its own configuration from the
+	// environment/.env file
+	ok, err := orbitkeys.New()
 	if err != nil {
 		log.Fatalf("Failed to initialize OrbitKeys: %v", err)
 	}
 
-	// Initialize OrbitKeys service
-	if err := ok.Init(); err != nil {
-		log.Fatalf("Failed to initialize service: %v", err)
-	}
-
 	// Ensure proper shutdown when done
-	defer ok.Shutdown()
+	defer ok.Close()
 
 	// Create our own Fiber app for custom routes
 	app := fiber.New()
@@ -76,6 +65,22 @@ func main() {
 		})
 	})
 
+	// A single product, gated by per-object ACL instead of the coarse
+	// products:read permission: this is its own group, not products, since
+	// Fiber runs every .Use() registered on a group for all of that
+	// group's routes regardless of order — reusing products would also
+	// run products.Use(APIKeyAuth("products:read")) first and reject a
+	// caller who only has an object-ACL grant before
+	// RequireObjectPermission ever runs.
+	productsACL := api.Group("/products-acl")
+	productsACL.Use(middleware.APIKeyAuth(""))
+	productsACL.Get("/:id", middleware.RequireObjectPermission("products", "id", models.CanRead), func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"message": "This is an object-ACL-protected product",
+			"id":      c.Params("id"),
+		})
+	})
+
 	// Example endpoint using custom data from API key
 	profile := api.Group("/profile")
 	profile.Use(middleware.APIKeyAuth("profile:read")) // Require profile:read permission
@@ -0,0 +1,100 @@
+// Package audit records security-relevant events — authentication attempts
+// and admin mutations — to one or more pluggable sinks, so deployments can
+// forward them to an external SIEM without giving up the local SQLite trail.
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/BasementPilot/orbit-keys/internal/models"
+)
+
+// Sink persists or forwards a single AuditLog entry. Implementations should
+// not mutate entry, and must be safe for concurrent use since Record may be
+// called from many request goroutines at once.
+type Sink interface {
+	Write(entry models.AuditLog) error
+}
+
+// activeSinks is the process-wide set of sinks installed by SetSinks. It
+// defaults to empty, so Record is a no-op until the application wires up at
+// least the default SQLite sink during startup.
+var activeSinks []Sink
+
+// SetSinks installs the sinks Record writes every entry to. It should be
+// called once during startup, mirroring policy.SetEnforcer and
+// handlers.SetConnectorRegistry.
+func SetSinks(sinks []Sink) {
+	activeSinks = sinks
+}
+
+// Record fills in entry.Timestamp if it's zero and writes entry to every
+// configured sink. The writes happen on a separate goroutine so a slow or
+// unreachable sink (e.g. WebhookSink's retries) can never add latency to
+// the request being audited; a write error is never returned to the
+// caller, but is reported through errFunc if one has been installed via
+// SetErrorHandler.
+func Record(entry models.AuditLog) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	sinks := activeSinks
+	go func() {
+		for _, sink := range sinks {
+			if err := sink.Write(entry); err != nil {
+				reportError(sink, err)
+			}
+		}
+	}()
+}
+
+// errFunc, when set via SetErrorHandler, is called with sink write failures
+// that Record itself can't surface to the request it's auditing.
+var errFunc func(error)
+
+// SetErrorHandler installs a callback invoked whenever a sink fails to
+// write an entry. Nil (the default) silently drops the error.
+func SetErrorHandler(f func(error)) {
+	errFunc = f
+}
+
+func reportError(sink Sink, err error) {
+	if errFunc == nil {
+		return
+	}
+	errFunc(fmtSinkError(sink, err))
+}
+
+func fmtSinkError(sink Sink, err error) error {
+	return &sinkError{sink: sink, err: err}
+}
+
+type sinkError struct {
+	sink Sink
+	err  error
+}
+
+func (e *sinkError) Error() string {
+	return "audit sink write failed: " + e.err.Error()
+}
+
+func (e *sinkError) Unwrap() error {
+	return e.err
+}
+
+// MarshalDetails encodes v as the JSON string expected in AuditLog.Details,
+// a convenience for callers building Details from a structured value
+// instead of hand-writing JSON. Returns an empty string if v is nil or
+// can't be marshaled.
+func MarshalDetails(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,38 @@
+package audit
+
+import "gorm.io/gorm"
+
+// Config carries the subset of config.Config audit sink construction needs.
+// It's a local type rather than config.Config, so audit doesn't import
+// config; orbitkeys.go fills it in from config.Config.
+type Config struct {
+	// File, if non-empty, additionally appends every entry as a line of
+	// JSON to this path.
+	File string
+
+	// WebhookURL, if non-empty, additionally POSTs every entry to this URL.
+	WebhookURL string
+}
+
+// BuildSinks constructs the sinks Record should fan out to: a SQLiteSink
+// backed by db is always included, plus a FileSink and/or WebhookSink when
+// cfg.File / cfg.WebhookURL are set. The returned closer, if non-nil, must
+// be closed during shutdown (it closes any FileSink that was opened).
+func BuildSinks(cfg Config, db *gorm.DB) (sinks []Sink, closer func() error, err error) {
+	sinks = append(sinks, NewSQLiteSink(db))
+
+	if cfg.File != "" {
+		fileSink, err := NewFileSink(cfg.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, fileSink)
+		closer = fileSink.Close
+	}
+
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, NewWebhookSink(cfg.WebhookURL))
+	}
+
+	return sinks, closer, nil
+}
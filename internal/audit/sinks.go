@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"gorm.io/gorm"
+)
+
+// SQLiteSink persists entries as rows in the AuditLog table, so they're
+// queryable through GET {BaseURL}/audit without any extra infrastructure.
+// It's the default sink and is always installed alongside any others the
+// operator configures.
+type SQLiteSink struct {
+	db *gorm.DB
+}
+
+// NewSQLiteSink wraps db, which must already have AuditLog migrated.
+func NewSQLiteSink(db *gorm.DB) *SQLiteSink {
+	return &SQLiteSink{db: db}
+}
+
+func (s *SQLiteSink) Write(entry models.AuditLog) error {
+	return s.db.Create(&entry).Error
+}
+
+// FileSink appends each entry to path as a line of JSON, for operators who
+// want to ship audit events into a log-shipping pipeline (Filebeat, Vector,
+// ...) rather than query them from OrbitKeys directly.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileSink opens path for appending, creating it if it doesn't exist.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	return &FileSink{
+		file: f,
+		enc:  json.NewEncoder(f),
+	}, nil
+}
+
+func (s *FileSink) Write(entry models.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(entry)
+}
+
+// Close closes the underlying file. It should be called during shutdown.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// WebhookSink POSTs each entry as JSON to a configured URL, for forwarding
+// events to an external SIEM. Failed deliveries are retried with a short
+// exponential backoff before being given up on.
+type WebhookSink struct {
+	url        string
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// NewWebhookSink returns a WebhookSink that posts to url, retrying a failed
+// delivery up to 3 times with exponential backoff starting at 200ms.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:        url,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+	}
+}
+
+func (s *WebhookSink) Write(entry models.AuditLog) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	delay := s.backoff
+	var lastErr error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("audit webhook returned status %d", resp.StatusCode)
+	}
+
+	return fmt.Errorf("audit webhook delivery failed after %d attempts: %w", s.maxRetries+1, lastErr)
+}
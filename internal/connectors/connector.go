@@ -0,0 +1,28 @@
+// Package connectors lets OrbitKeys delegate authentication to an external
+// identity provider and mint an API key from the resulting identity,
+// instead of requiring every credential to be provisioned with the root key.
+package connectors
+
+import "context"
+
+// Identity represents the authenticated subject returned by a Connector
+// after a successful external handshake.
+type Identity struct {
+	Subject string
+	Email   string
+	Groups  []string
+}
+
+// Connector performs an external identity handshake and resolves the
+// caller's Identity. Implementations are registered with a Registry under
+// a unique Name, which becomes the {connector} path segment in
+// /auth/{connector}/login and /auth/{connector}/callback.
+type Connector interface {
+	// Name returns the connector's unique, URL-safe identifier.
+	Name() string
+
+	// Callback exchanges a provider-specific credential (an OIDC
+	// authorization code, or "username:password" for StaticPasswordConnector)
+	// for the caller's Identity.
+	Callback(ctx context.Context, code string) (Identity, error)
+}
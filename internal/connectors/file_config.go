@@ -0,0 +1,72 @@
+package connectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the on-disk schema for ORBITKEYS_CONNECTORS_FILE: a list of
+// enabled connectors, their provider credentials, and a groups-to-role
+// mapping applied after a successful handshake.
+type FileConfig struct {
+	Connectors []ConnectorFileEntry `json:"connectors" yaml:"connectors"`
+}
+
+// ConnectorFileEntry configures a single connector. Type selects which
+// fields are meaningful: "oidc" requires IssuerURL/ClientID/ClientSecret/
+// RedirectURL, "static" requires Users.
+type ConnectorFileEntry struct {
+	Type         string                `json:"type" yaml:"type"`
+	Name         string                `json:"name" yaml:"name"`
+	IssuerURL    string                `json:"issuer_url,omitempty" yaml:"issuer_url,omitempty"`
+	ClientID     string                `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string                `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	RedirectURL  string                `json:"redirect_url,omitempty" yaml:"redirect_url,omitempty"`
+	Users        map[string]StaticUser `json:"users,omitempty" yaml:"users,omitempty"`
+	GroupRoles   map[string]string     `json:"group_roles" yaml:"group_roles"`
+}
+
+// LoadFileConfig reads and parses path, choosing JSON or YAML by extension
+// (".json" is parsed as JSON, everything else as YAML).
+func LoadFileConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connectors file: %w", err)
+	}
+
+	cfg := &FileConfig{}
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse connectors file as JSON: %w", err)
+		}
+	} else if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse connectors file as YAML: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// BuildRegistry constructs a Registry from a parsed FileConfig, instantiating
+// the concrete Connector for each configured entry.
+func BuildRegistry(cfg *FileConfig) (*Registry, error) {
+	registry := NewRegistry()
+
+	for _, entry := range cfg.Connectors {
+		switch entry.Type {
+		case "oidc":
+			conn := NewOIDCConnector(entry.Name, entry.IssuerURL, entry.ClientID, entry.ClientSecret, entry.RedirectURL)
+			registry.Register(conn, entry.GroupRoles)
+		case "static":
+			conn := &StaticPasswordConnector{ConnectorName: entry.Name, Users: entry.Users}
+			registry.Register(conn, entry.GroupRoles)
+		default:
+			return nil, fmt.Errorf("unknown connector type %q for connector %q", entry.Type, entry.Name)
+		}
+	}
+
+	return registry, nil
+}
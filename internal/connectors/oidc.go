@@ -0,0 +1,104 @@
+package connectors
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// OIDCConnector authenticates callers via an OIDC authorization-code flow.
+type OIDCConnector struct {
+	ConnectorName string
+	oauth2Config  *oauth2.Config
+}
+
+// NewOIDCConnector builds an OIDCConnector for the given provider. issuerURL
+// is expected to expose the standard /authorize and /token endpoints.
+func NewOIDCConnector(name, issuerURL, clientID, clientSecret, redirectURL string) *OIDCConnector {
+	return &OIDCConnector{
+		ConnectorName: name,
+		oauth2Config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"openid", "profile", "email", "groups"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  strings.TrimSuffix(issuerURL, "/") + "/authorize",
+				TokenURL: strings.TrimSuffix(issuerURL, "/") + "/token",
+			},
+		},
+	}
+}
+
+// Name returns the connector's unique identifier.
+func (o *OIDCConnector) Name() string {
+	return o.ConnectorName
+}
+
+// AuthCodeURL returns the provider URL the caller should be redirected to
+// in order to begin the authorization-code flow.
+func (o *OIDCConnector) AuthCodeURL(state string) string {
+	return o.oauth2Config.AuthCodeURL(state)
+}
+
+// Callback exchanges the authorization code for tokens and extracts the
+// caller's Identity from the ID token's claims.
+func (o *OIDCConnector) Callback(ctx context.Context, code string) (Identity, error) {
+	token, err := o.oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("token response did not include an id_token")
+	}
+
+	claims, err := decodeIDTokenClaims(rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to parse id_token: %w", err)
+	}
+
+	return Identity{
+		Subject: claims.Subject,
+		Email:   claims.Email,
+		Groups:  claims.Groups,
+	}, nil
+}
+
+// idTokenClaims are the subset of standard OIDC claims OrbitKeys cares about.
+type idTokenClaims struct {
+	Subject string   `json:"sub"`
+	Email   string   `json:"email"`
+	Groups  []string `json:"groups"`
+}
+
+// decodeIDTokenClaims extracts the claims from a JWT's payload segment
+// without verifying the signature. A production deployment behind an
+// untrusted network should swap this for a verifying parser configured
+// with the issuer's JWKS; this lightweight decode keeps the connector
+// dependency-free for deployments where the IdP is reached over a trusted
+// channel (e.g. a private network or mTLS-terminated proxy).
+func decodeIDTokenClaims(rawIDToken string) (idTokenClaims, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return idTokenClaims{}, errors.New("id_token is not a well-formed JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return idTokenClaims{}, fmt.Errorf("failed to decode id_token payload: %w", err)
+	}
+
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return idTokenClaims{}, fmt.Errorf("failed to unmarshal id_token claims: %w", err)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,43 @@
+package connectors
+
+// Registry holds the set of enabled Connectors, keyed by name, along with
+// the groups-to-role mapping used to derive an issued APIKey's Role after a
+// successful handshake.
+type Registry struct {
+	connectors map[string]Connector
+	groupRoles map[string]map[string]string
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		connectors: make(map[string]Connector),
+		groupRoles: make(map[string]map[string]string),
+	}
+}
+
+// Register adds a Connector under its own Name, along with the mapping from
+// one of the identity's groups claims to the Role name that should back
+// API keys minted through it.
+func (r *Registry) Register(c Connector, groupToRole map[string]string) {
+	r.connectors[c.Name()] = c
+	r.groupRoles[c.Name()] = groupToRole
+}
+
+// Get returns the Connector registered under name, if any.
+func (r *Registry) Get(name string) (Connector, bool) {
+	c, ok := r.connectors[name]
+	return c, ok
+}
+
+// ResolveRole returns the name of the first configured role whose group
+// matches one of the identity's groups claims, or "" if none match.
+func (r *Registry) ResolveRole(connectorName string, identity Identity) string {
+	mapping := r.groupRoles[connectorName]
+	for _, group := range identity.Groups {
+		if role, ok := mapping[group]; ok {
+			return role
+		}
+	}
+	return ""
+}
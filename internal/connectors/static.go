@@ -0,0 +1,66 @@
+package connectors
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+)
+
+// dummyPassword is compared against for an unknown username, in place of a
+// real one, so Callback's timing doesn't depend on whether the username
+// exists.
+const dummyPassword = "orbitkeys-static-connector-dummy-password"
+
+// StaticUser is a single env/file-configured username/password entry
+// accepted by a StaticPasswordConnector.
+type StaticUser struct {
+	Password string   `json:"password" yaml:"password"`
+	Email    string   `json:"email" yaml:"email"`
+	Groups   []string `json:"groups" yaml:"groups"`
+}
+
+// StaticPasswordConnector authenticates against a fixed set of configured
+// username/password pairs. It's useful for CI and local development where
+// standing up a full OIDC provider isn't worth it.
+type StaticPasswordConnector struct {
+	ConnectorName string
+	Users         map[string]StaticUser
+}
+
+// Name returns the connector's unique identifier.
+func (s *StaticPasswordConnector) Name() string {
+	return s.ConnectorName
+}
+
+// Callback authenticates a "username:password" credential (there being no
+// redirect flow for this connector) and resolves the matching StaticUser's
+// Identity. The password comparison is constant-time to avoid leaking
+// information about which username exists via timing.
+func (s *StaticPasswordConnector) Callback(ctx context.Context, code string) (Identity, error) {
+	username, password, ok := strings.Cut(code, ":")
+	if !ok {
+		return Identity{}, errors.New("static connector expects \"username:password\"")
+	}
+
+	// Always run the comparison, even for an unknown username, so that
+	// which branch taken doesn't leak via timing: short-circuiting on
+	// !exists would let a nonexistent user return measurably faster than
+	// a wrong password for a real one.
+	user, exists := s.Users[username]
+	comparePassword := user.Password
+	if !exists {
+		comparePassword = dummyPassword
+	}
+	match := subtle.ConstantTimeCompare([]byte(comparePassword), []byte(password)) == 1
+
+	if !exists || !match {
+		return Identity{}, errors.New("invalid credentials")
+	}
+
+	return Identity{
+		Subject: username,
+		Email:   user.Email,
+		Groups:  user.Groups,
+	}, nil
+}
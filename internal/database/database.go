@@ -11,6 +11,7 @@ import (
 
 	"github.com/BasementPilot/orbit-keys/config"
 	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/utils"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -60,11 +61,68 @@ func InitDB(cfg *config.Config) error {
 	}
 
 	// Run migrations
-	err = DB.AutoMigrate(&models.Role{}, &models.APIKey{})
+	err = DB.AutoMigrate(&models.Role{}, &models.APIKey{}, &models.AppRole{}, &models.SecretID{}, &models.IssuedToken{}, &models.ObjectACL{}, &models.ACLEntry{}, &models.AuditLog{})
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// Install the pepper/algorithm used to hash API keys before anything
+	// (including the rehash pass below) touches one.
+	utils.SetKeyHashConfig(cfg.KeyPepper, cfg.KeyHashAlgorithm)
+	utils.SetAPIKeyJWTSigningKey([]byte(cfg.APIKeyJWTSigningKey))
+
+	keyGen, err := utils.SelectKeyGenerator(cfg.KeyGenerator, cfg.KeyEncoding)
+	if err != nil {
+		return fmt.Errorf("failed to initialize key generator: %w", err)
+	}
+	utils.SetKeyGenerator(keyGen)
+
+	if err := rehashLegacyPlaintextKeys(DB); err != nil {
+		return fmt.Errorf("failed to rehash legacy plaintext API keys: %w", err)
+	}
+
+	return nil
+}
+
+// legacyAPIKeyRow reads the "key" column of api_keys directly, via raw SQL,
+// since models.APIKey no longer maps it (see models.APIKey.KeyHash). On an
+// install that never had plaintext keys, the column doesn't exist at all,
+// the query below fails, and that failure is treated as nothing to migrate.
+type legacyAPIKeyRow struct {
+	ID  uint
+	Key string
+}
+
+// rehashLegacyPlaintextKeys upgrades any row still carrying a plaintext key
+// from before API keys were hashed at rest: it computes KeyHash/KeyPrefix
+// from the legacy value and persists them, so the key keeps authenticating
+// under the new, hashed lookup path without requiring re-issuance.
+func rehashLegacyPlaintextKeys(db *gorm.DB) error {
+	var rows []legacyAPIKeyRow
+	query := "SELECT id, key FROM api_keys WHERE key IS NOT NULL AND key != '' AND (key_hash IS NULL OR key_hash = '')"
+	if err := db.Raw(query).Scan(&rows).Error; err != nil {
+		// No legacy "key" column at all is the common case for a fresh
+		// install; AutoMigrate never creates one, since models.APIKey.Key
+		// is gorm:"-". Nothing to migrate.
+		return nil
+	}
+
+	for _, row := range rows {
+		hash, err := utils.HashAPIKey(row.Key)
+		if err != nil {
+			return fmt.Errorf("failed to hash legacy API key %d: %w", row.ID, err)
+		}
+
+		if err := db.Exec(
+			"UPDATE api_keys SET key_hash = ?, key_prefix = ? WHERE id = ?",
+			hash, utils.KeyLookupPrefix(row.Key), row.ID,
+		).Error; err != nil {
+			return fmt.Errorf("failed to persist rehashed API key %d: %w", row.ID, err)
+		}
+
+		log.Printf("Migrated API key %d from plaintext storage to a hashed lookup", row.ID)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"gorm.io/gorm"
+)
+
+// GetObjectACL handles requests to retrieve the ACL for a single object,
+// identified by :objectType and :objectId in the route.
+//
+// Returns:
+// - 200 OK with the ObjectACL (and its entries) on success, or an empty
+//   entry list if no ACL has been created for the object yet
+// - 500 Internal Server Error if a database error occurs
+func GetObjectACL(c *fiber.Ctx) error {
+	objectType := c.Params("objectType")
+	objectID := c.Params("objectId")
+
+	db := database.GetDB()
+	var acl models.ObjectACL
+	err := db.Preload("Entries").
+		Where("object_type = ? AND object_id = ?", objectType, objectID).
+		First(&acl).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// No ACL yet is not an error - it just means nobody has been granted
+		// object-level access beyond the coarse role permissions.
+		return c.JSON(models.ObjectACL{
+			ObjectType: objectType,
+			ObjectID:   objectID,
+			Entries:    []models.ACLEntry{},
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve ACL",
+		})
+	}
+
+	return c.JSON(acl)
+}
+
+// PutObjectACL handles requests to atomically add and/or remove entries from
+// an object's ACL via an AccessControlChangeList. The ObjectACL row is
+// created on first use.
+//
+// Returns:
+// - 200 OK with the updated ObjectACL on success
+// - 400 Bad Request if the request body is invalid
+// - 500 Internal Server Error if a database error occurs
+func PutObjectACL(c *fiber.Ctx) error {
+	objectType := c.Params("objectType")
+	objectID := c.Params("objectId")
+
+	req := new(models.AccessControlChangeList)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	db := database.GetDB()
+
+	var acl models.ObjectACL
+	if err := db.Where("object_type = ? AND object_id = ?", objectType, objectID).First(&acl).Error; err != nil {
+		acl = models.ObjectACL{ObjectType: objectType, ObjectID: objectID}
+		if err := db.Create(&acl).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create ACL: " + err.Error(),
+			})
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range req.Remove {
+			q := tx.Where("object_acl_id = ? AND permission_level = ?", acl.ID, entry.PermissionLevel)
+			if entry.APIKeyID != nil {
+				q = q.Where("api_key_id = ?", *entry.APIKeyID)
+			}
+			if entry.RoleID != nil {
+				q = q.Where("role_id = ?", *entry.RoleID)
+			}
+			if err := q.Delete(&models.ACLEntry{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range req.Add {
+			entry.ID = 0
+			entry.ObjectACLID = acl.ID
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update ACL: " + err.Error(),
+		})
+	}
+
+	if err := db.Preload("Entries").First(&acl, acl.ID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reload ACL",
+		})
+	}
+
+	return c.JSON(acl)
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/audit"
 	"github.com/BasementPilot/orbit-keys/internal/database"
 	"github.com/BasementPilot/orbit-keys/internal/models"
 	"github.com/BasementPilot/orbit-keys/utils"
@@ -16,9 +19,20 @@ import (
 // It specifies the role to associate with the key, an optional description,
 // and an optional expiration time in days.
 type CreateAPIKeyRequest struct {
-	RoleID      uint   `json:"role_id" validate:"required"`
-	Description string `json:"description"`
-	ExpiresIn   *int   `json:"expires_in"` // Expiration in days, nil means no expiration
+	RoleID      uint     `json:"role_id" validate:"required"`
+	Description string   `json:"description"`
+	ExpiresIn   *int     `json:"expires_in"` // Expiration in days, nil means no expiration
+	Scopes      []string `json:"scopes"`     // Optional; narrows the key to a subset of the role's permissions
+	Owner       string   `json:"owner"`      // Optional; identifies who the key was issued to, used by GetMyAPIKeys/DeleteMyAPIKey
+	Format      string   `json:"format"`     // Optional; "opaque" (default) or "jwt", see utils.KeyFormat
+
+	// ReadOnly and MaxClass cap what this key may do regardless of its
+	// role's permissions; see models.CheckPermissionForAction. MaxClass, if
+	// set, takes precedence over ReadOnly. Both are stored on the key's row
+	// only: a "jwt"-format key is authenticated from its token's claims,
+	// which carry neither, so neither caps a "jwt" key.
+	ReadOnly bool                `json:"read_only"`
+	MaxClass *models.ActionClass `json:"max_class"`
 }
 
 // CreateAPIKey handles requests to create a new API key.
@@ -61,42 +75,148 @@ func CreateAPIKey(c *fiber.Ctx) error {
 		expiresIn = &days
 	}
 
+	// Convert scopes array to string, validating each entry the same way
+	// CreateRole validates its permissions.
+	scopes := ""
+	for i, s := range req.Scopes {
+		if !models.ValidatePermissionFormat(s) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid scope format: " + s,
+			})
+		}
+
+		if i > 0 {
+			scopes += ","
+		}
+		scopes += s
+	}
+
+	// Validate format before we go generate anything
+	format := utils.KeyFormat(req.Format)
+	if format != "" && format != utils.FormatOpaque && format != utils.FormatJWT {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid format: must be \"opaque\" or \"jwt\"",
+		})
+	}
+
 	// Create API key
-	apiKey, err := utils.CreateAPIKey(req.RoleID, req.Description, expiresIn)
+	apiKey, err := utils.CreateAPIKey(req.RoleID, req.Description, "", scopes, format, expiresIn)
 	if err != nil {
+		auditAPIKeyEvent(c, "api_key:create", "", "failure", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate API key",
 		})
 	}
+	apiKey.Owner = req.Owner
+	apiKey.ReadOnly = req.ReadOnly
+	apiKey.MaxClass = req.MaxClass
 
 	// Save to database
 	if err := db.Create(apiKey).Error; err != nil {
+		auditAPIKeyEvent(c, "api_key:create", "", "failure", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save API key",
 		})
 	}
 
+	auditAPIKeyEvent(c, "api_key:create", fmt.Sprint(apiKey.ID), "success", nil)
 	return c.Status(fiber.StatusCreated).JSON(apiKey)
 }
 
-// GetAPIKeys handles requests to retrieve all API keys from the database.
-// It returns a JSON array of all API keys with their associated roles preloaded.
+// GetAPIKeys handles requests to list API keys, with optional filtering,
+// sorting, and pagination so the response stays bounded no matter how many
+// keys exist.
+//
+// Query parameters (all optional):
+//   - role_id: only keys belonging to this role
+//   - expired: "true" for only expired keys, "false" for only unexpired ones
+//   - q: substring match against Description
+//   - sort: "created_at" (default) or "last_used_at", always descending
+//   - page, page_size: pagination, defaulting to 1 and 50 (page_size capped at 200)
 //
 // Returns:
-// - 200 OK with an array of API keys on success
+// - 200 OK with a {total, page, page_size, data} envelope and an
+//   X-Total-Count header set to the unpaginated match count
+// - 400 Bad Request if a filter value is malformed
 // - 500 Internal Server Error if a database error occurs
 func GetAPIKeys(c *fiber.Ctx) error {
-	var apiKeys []models.APIKey
 	db := database.GetDB()
-	
-	// Get all API keys with their roles
-	if err := db.Preload("Role").Find(&apiKeys).Error; err != nil {
+	query := db.Model(&models.APIKey{})
+
+	if roleID := c.Query("role_id"); roleID != "" {
+		id, err := strconv.ParseUint(roleID, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid role_id",
+			})
+		}
+		query = query.Where("role_id = ?", id)
+	}
+
+	if expired := c.Query("expired"); expired != "" {
+		wantExpired, err := strconv.ParseBool(expired)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid expired, expected true or false",
+			})
+		}
+		if wantExpired {
+			query = query.Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now())
+		} else {
+			query = query.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+		}
+	}
+
+	if q := c.Query("q"); q != "" {
+		query = query.Where("description LIKE ?", "%"+q+"%")
+	}
+
+	sortColumn := "created_at"
+	if sort := c.Query("sort"); sort != "" {
+		switch sort {
+		case "created_at", "last_used_at":
+			sortColumn = sort
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid sort, expected created_at or last_used_at",
+			})
+		}
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+
+	pageSize := 50
+	if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+		pageSize = ps
+	}
+	if pageSize > 200 {
+		pageSize = 200
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count API keys",
+		})
+	}
+
+	var apiKeys []models.APIKey
+	if err := query.Preload("Role").Order(sortColumn + " DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&apiKeys).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve API keys",
 		})
 	}
 
-	return c.JSON(apiKeys)
+	c.Set("X-Total-Count", strconv.FormatInt(total, 10))
+	return c.JSON(fiber.Map{
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"data":      apiKeys,
+	})
 }
 
 // GetAPIKey handles requests to retrieve a single API key by its ID.
@@ -147,6 +267,68 @@ func DeleteAPIKey(c *fiber.Ctx) error {
 			"error": "Failed to delete API key",
 		})
 	}
+	utils.InvalidateAPIKeyCache(apiKey.KeyPrefix)
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// GetMyAPIKeys handles requests to list the keys sharing the caller's own
+// Owner, so a team can see everything it has issued without needing the
+// root key. The caller's key is read from "apiKey", set by APIKeyAuth.
+//
+// Returns:
+// - 200 OK with the matching API keys, or an empty list if the caller's key
+//   has no Owner set
+// - 500 Internal Server Error if a database error occurs
+func GetMyAPIKeys(c *fiber.Ctx) error {
+	caller, ok := c.Locals("apiKey").(models.APIKey)
+	if !ok || caller.Owner == "" {
+		return c.JSON([]models.APIKey{})
+	}
+
+	db := database.GetDB()
+	var apiKeys []models.APIKey
+	if err := db.Preload("Role").Where("owner = ?", caller.Owner).Find(&apiKeys).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve API keys",
+		})
+	}
+
+	return c.JSON(apiKeys)
+}
+
+// DeleteMyAPIKey handles self-revocation: it deletes the API key identified
+// by :id only if it shares the caller's own Owner, so a key can never be
+// used to delete another owner's key.
+//
+// Returns:
+// - 204 No Content on successful deletion
+// - 404 Not Found if the API key doesn't exist or isn't owned by the caller
+// - 500 Internal Server Error if a database error occurs
+func DeleteMyAPIKey(c *fiber.Ctx) error {
+	caller, ok := c.Locals("apiKey").(models.APIKey)
+	if !ok || caller.Owner == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key not found",
+		})
+	}
+
+	id := c.Params("id")
+
+	db := database.GetDB()
+	var apiKey models.APIKey
+	if err := db.Where("id = ? AND owner = ?", id, caller.Owner).First(&apiKey).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key not found",
+		})
+	}
+
+	if err := db.Delete(&apiKey).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete API key",
+		})
+	}
+	utils.InvalidateAPIKeyCache(apiKey.KeyPrefix)
 
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
@@ -169,15 +351,16 @@ func LookupAPIKey(c *fiber.Ctx) error {
 		})
 	}
 
-	var apiKey models.APIKey
 	db := database.GetDB()
-	
+
 	// Find API key by key value with its role
-	if err := db.Preload("Role").Where("key = ?", key).First(&apiKey).Error; err != nil {
+	foundKey, err := utils.FindAPIKeyByPlaintext(db, key)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "API key not found",
 		})
 	}
+	apiKey := *foundKey
 
 	// Check if expired
 	if apiKey.IsExpired() {
@@ -218,15 +401,16 @@ func ValidateAPIKeyPermission(c *fiber.Ctx) error {
 		})
 	}
 
-	var apiKey models.APIKey
 	db := database.GetDB()
-	
+
 	// Find API key by key value with its role
-	if err := db.Preload("Role").Where("key = ?", key).First(&apiKey).Error; err != nil {
+	foundKey, err := utils.FindAPIKeyByPlaintext(db, key)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "API key not found",
 		})
 	}
+	apiKey := *foundKey
 
 	// Check if expired
 	if apiKey.IsExpired() {
@@ -235,8 +419,9 @@ func ValidateAPIKeyPermission(c *fiber.Ctx) error {
 		})
 	}
 
-	// Check if the key has the required permission
-	hasPermission := apiKey.Role.HasPermission(permission)
+	// Check if the key has the required permission: its role must grant
+	// it, and, if the key has scopes, they must allow it too.
+	hasPermission := apiKey.Role.HasPermission(permission) && apiKey.ScopesAllow(permission)
 	
 	// Update last used timestamp
 	go func(db *gorm.DB, apiKey *models.APIKey) {
@@ -308,6 +493,214 @@ func UpdateAPIKeyExpiration(c *fiber.Ctx) error {
 			"error": "Failed to update API key expiration",
 		})
 	}
+	utils.InvalidateAPIKeyCache(apiKey.KeyPrefix)
+
+	return c.JSON(apiKey)
+}
+
+// RotateAPIKeyRequest defines the request structure for rotating an API key.
+type RotateAPIKeyRequest struct {
+	GracePeriod *int `json:"grace_period"` // Seconds the old key stays valid alongside the new one, 0/nil means immediate cutover
+}
+
+// RotateAPIKey issues a new secret for an existing API key while keeping its
+// ID, RoleID, Description, CreatedAt, and ExpiresAt unchanged, so identity
+// and audit history carry over across the rotation. When GracePeriod is
+// provided, the old key's hash is preserved as PreviousKeyHash and stays
+// valid until PreviousKeyExpiresAt, letting callers roll keys without a
+// coordinated cutover.
+//
+// Returns:
+// - 200 OK with the rotated API key, including the new plaintext key once
+// - 400 Bad Request if the ID is invalid or the request body is malformed
+// - 404 Not Found if the API key doesn't exist
+// - 500 Internal Server Error if a database or key generation error occurs
+func RotateAPIKey(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key ID",
+		})
+	}
+
+	req := new(RotateAPIKeyRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	db := database.GetDB()
+	var apiKey models.APIKey
+	if err := db.First(&apiKey, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key not found",
+		})
+	}
+
+	var graceDuration time.Duration
+	if req.GracePeriod != nil && *req.GracePeriod > 0 {
+		graceDuration = time.Duration(*req.GracePeriod) * time.Second
+	}
+
+	if err := utils.RotateAPIKey(db, &apiKey, graceDuration); err != nil {
+		auditAPIKeyEvent(c, "api_key:rotate", fmt.Sprint(apiKey.ID), "failure", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to rotate API key",
+		})
+	}
+
+	auditAPIKeyEvent(c, "api_key:rotate", fmt.Sprint(apiKey.ID), "success", nil)
+	return c.JSON(apiKey)
+}
+
+// RevokeAPIKey immediately invalidates a single API key by ID, without
+// deleting it, so it remains visible in GetAPIKeys/audit history. For
+// invalidating many keys at once by filter, see RevokeAPIKeys.
+//
+// Returns:
+// - 200 OK with the revoked API key on success
+// - 400 Bad Request if the ID is invalid
+// - 404 Not Found if the API key doesn't exist
+// - 500 Internal Server Error if a database error occurs
+func RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key ID",
+		})
+	}
+
+	db := database.GetDB()
+	var apiKey models.APIKey
+	if err := db.First(&apiKey, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key not found",
+		})
+	}
+
+	if err := utils.RevokeAPIKey(db, &apiKey); err != nil {
+		auditAPIKeyEvent(c, "api_key:revoke", fmt.Sprint(apiKey.ID), "failure", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke API key",
+		})
+	}
 
+	auditAPIKeyEvent(c, "api_key:revoke", fmt.Sprint(apiKey.ID), "success", nil)
 	return c.JSON(apiKey)
-} 
\ No newline at end of file
+}
+
+// RevokeAPIKeysRequest defines the request structure for bulk key revocation.
+// At least one filter must be provided; all provided filters are ANDed
+// together, except IDs, which further narrows the result to that explicit
+// set. Matching keys are expired immediately (ExpiresAt set to now), not
+// deleted, so audit history and role assignments are preserved.
+type RevokeAPIKeysRequest struct {
+	RoleID            *uint      `json:"role_id"`
+	DescriptionPrefix string     `json:"description_prefix"`
+	UnusedSince       *time.Time `json:"unused_since"` // expires keys whose LastUsedAt is before this time (or never used)
+	IDs               []uint     `json:"ids"`
+}
+
+// RevokeAPIKeys handles bulk key revocation for incident response, e.g.
+// rotating everything issued to a compromised role or killing every key
+// that hasn't been used in 90 days. Rather than requiring N individual
+// DELETE/PATCH calls, it expires every matching key in one transaction and
+// invalidates each from the lookup cache.
+//
+// Returns:
+// - 200 OK with the count of revoked keys
+// - 400 Bad Request if the request body is malformed or no filter is given
+// - 500 Internal Server Error if a database error occurs
+func RevokeAPIKeys(c *fiber.Ctx) error {
+	req := new(RevokeAPIKeysRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.RoleID == nil && req.DescriptionPrefix == "" && req.UnusedSince == nil && len(req.IDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "At least one filter (role_id, description_prefix, unused_since, or ids) is required",
+		})
+	}
+
+	db := database.GetDB()
+	query := db.Model(&models.APIKey{})
+
+	if req.RoleID != nil {
+		query = query.Where("role_id = ?", *req.RoleID)
+	}
+	if req.DescriptionPrefix != "" {
+		query = query.Where("description LIKE ?", req.DescriptionPrefix+"%")
+	}
+	if req.UnusedSince != nil {
+		query = query.Where("last_used_at IS NULL OR last_used_at < ?", *req.UnusedSince)
+	}
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+
+	var matched []models.APIKey
+	if err := query.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&matched).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to find matching API keys",
+		})
+	}
+
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range matched {
+			if err := tx.Model(&models.APIKey{}).Where("id = ?", key.ID).Update("expires_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		auditAPIKeyEvent(c, "api_key:bulk_revoke", "", "failure", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke API keys: " + err.Error(),
+		})
+	}
+
+	for _, key := range matched {
+		utils.InvalidateAPIKeyCache(key.KeyPrefix)
+	}
+
+	ids := make([]string, len(matched))
+	for i, key := range matched {
+		ids[i] = fmt.Sprint(key.ID)
+	}
+	auditAPIKeyEvent(c, "api_key:bulk_revoke", strings.Join(ids, ","), "success", nil)
+
+	return c.JSON(fiber.Map{
+		"revoked": len(matched),
+	})
+}
+
+// auditAPIKeyEvent records an API key mutation to the audit log. The actor
+// is whatever APIKeyAuth/RootAPIKeyAuth populated into c.Locals("apiKey"),
+// mirroring auditRoleEvent in roles.go.
+func auditAPIKeyEvent(c *fiber.Ctx, action, resourceID, result string, err error) {
+	details := ""
+	if err != nil {
+		details = audit.MarshalDetails(fiber.Map{"error": err.Error()})
+	}
+
+	var actorKeyID *uint
+	if key, ok := c.Locals("apiKey").(models.APIKey); ok {
+		actorKeyID = &key.ID
+	}
+
+	audit.Record(models.AuditLog{
+		ActorKeyID: actorKeyID,
+		ActorIP:    c.IP(),
+		Action:     action,
+		Resource:   "api_key",
+		ResourceID: resourceID,
+		Result:     result,
+		Details:    details,
+	})
+}
@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"net"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/internal/policy"
+	"github.com/BasementPilot/orbit-keys/utils"
+	"gorm.io/gorm"
+)
+
+// appRoleDefaultSecretIDTTL and appRoleDefaultTokenTTL back-fill AppRoles
+// created without an explicit TTL. They are populated from config.Config by
+// SetAppRoleDefaults during application startup.
+var (
+	appRoleDefaultSecretIDTTL time.Duration
+	appRoleDefaultTokenTTL    time.Duration
+)
+
+// SetAppRoleDefaults configures the fallback TTLs applied to AppRoles created
+// without an explicit secret_id_ttl/token_ttl. It should be called once
+// during startup with the values loaded from ORBITKEYS_APPROLE_SECRET_ID_TTL
+// and ORBITKEYS_APPROLE_TOKEN_TTL.
+func SetAppRoleDefaults(secretIDTTL, tokenTTL time.Duration) {
+	appRoleDefaultSecretIDTTL = secretIDTTL
+	appRoleDefaultTokenTTL = tokenTTL
+}
+
+// CreateAppRoleRequest defines the request structure for creating a new AppRole.
+// BoundPermissions follows the same "resource:action" grammar as Role.Permissions.
+type CreateAppRoleRequest struct {
+	Name             string   `json:"name" validate:"required"`
+	Description      string   `json:"description"`
+	BoundPermissions []string `json:"bound_permissions" validate:"required"`
+	SecretIDTTL      *int     `json:"secret_id_ttl"`      // Seconds, 0/nil uses the configured default (a zero default means no expiration)
+	TokenTTL         *int     `json:"token_ttl"`          // Seconds, 0/nil uses the configured default (a zero default means no expiration)
+	SecretIDNumUses  *int     `json:"secret_id_num_uses"` // 0/nil means unlimited
+}
+
+// CreateAppRole handles requests to create a new AppRole.
+// It validates the bound permissions, generates a non-secret role_id, and
+// stores the AppRole in the database.
+//
+// Returns:
+// - 201 Created with the created AppRole on success
+// - 400 Bad Request if the request body is invalid or contains invalid permissions
+// - 500 Internal Server Error if a database or ID generation error occurs
+func CreateAppRole(c *fiber.Ctx) error {
+	req := new(CreateAppRoleRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name is required",
+		})
+	}
+
+	boundPermissions := ""
+	for i, p := range req.BoundPermissions {
+		if !models.ValidatePermissionFormat(p) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid permission format: " + p,
+			})
+		}
+		if i > 0 {
+			boundPermissions += ","
+		}
+		boundPermissions += p
+	}
+
+	roleID, err := utils.GenerateRoleID()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate role_id",
+		})
+	}
+
+	appRole := models.AppRole{
+		RoleID:           roleID,
+		Name:             req.Name,
+		Description:      req.Description,
+		BoundPermissions: boundPermissions,
+		SecretIDTTL:      secondsToDurationOrDefault(req.SecretIDTTL, appRoleDefaultSecretIDTTL),
+		TokenTTL:         secondsToDurationOrDefault(req.TokenTTL, appRoleDefaultTokenTTL),
+		SecretIDNumUses:  intOrZero(req.SecretIDNumUses),
+	}
+
+	db := database.GetDB()
+	if err := db.Create(&appRole).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create AppRole: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(appRole)
+}
+
+// GetAppRoles handles requests to retrieve all AppRoles from the database.
+//
+// Returns:
+// - 200 OK with an array of AppRoles on success
+// - 500 Internal Server Error if a database error occurs
+func GetAppRoles(c *fiber.Ctx) error {
+	var appRoles []models.AppRole
+	db := database.GetDB()
+
+	if err := db.Find(&appRoles).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve AppRoles",
+		})
+	}
+
+	return c.JSON(appRoles)
+}
+
+// GetAppRole handles requests to retrieve a single AppRole by its numeric ID.
+//
+// Returns:
+// - 200 OK with the requested AppRole on success
+// - 404 Not Found if the AppRole doesn't exist
+func GetAppRole(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var appRole models.AppRole
+	db := database.GetDB()
+
+	if err := db.Where("id = ?", id).First(&appRole).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "AppRole not found",
+		})
+	}
+
+	return c.JSON(appRole)
+}
+
+// DeleteAppRole handles requests to delete an AppRole by its numeric ID.
+// Associated SecretIDs are deleted with it through GORM's association
+// delete (Select("SecretIDs")).
+//
+// Returns:
+// - 204 No Content on successful deletion
+// - 500 Internal Server Error if a database error occurs
+func DeleteAppRole(c *fiber.Ctx) error {
+	id := c.Params("id")
+	db := database.GetDB()
+
+	if err := db.Select("SecretIDs").Delete(&models.AppRole{}, id).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete AppRole",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// GenerateAppRoleSecretIDRequest is the optional request body for
+// GenerateAppRoleSecretID. CIDRBound, if set, restricts the secret_id to
+// logins from clients within that network; see AppRoleLogin.
+type GenerateAppRoleSecretIDRequest struct {
+	CIDRBound string `json:"cidr_bound"`
+}
+
+// GenerateAppRoleSecretID creates a new SecretID bound to an AppRole and
+// returns the plaintext value exactly once; only its hash is persisted.
+//
+// Returns:
+// - 201 Created with the plaintext secret_id on success
+// - 400 Bad Request if cidr_bound is not a valid CIDR
+// - 404 Not Found if the AppRole doesn't exist
+// - 500 Internal Server Error if a database or generation error occurs
+func GenerateAppRoleSecretID(c *fiber.Ctx) error {
+	id := c.Params("id")
+	db := database.GetDB()
+
+	var appRole models.AppRole
+	if err := db.Where("id = ?", id).First(&appRole).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "AppRole not found",
+		})
+	}
+
+	req := new(GenerateAppRoleSecretIDRequest)
+	// A body is optional here since CIDRBound itself is optional; only a
+	// malformed (non-empty) one is rejected.
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if req.CIDRBound != "" {
+		if _, _, err := net.ParseCIDR(req.CIDRBound); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "cidr_bound must be a valid CIDR",
+			})
+		}
+	}
+
+	secretID, err := utils.GenerateSecretID()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate secret_id",
+		})
+	}
+
+	numUses := appRole.SecretIDNumUses
+	if numUses == 0 {
+		numUses = -1 // unlimited
+	}
+
+	record := models.SecretID{
+		AppRoleID:        appRole.ID,
+		SecretIDHash:     utils.HashSecretID(secretID),
+		NumUsesRemaining: numUses,
+		CIDRBound:        req.CIDRBound,
+	}
+
+	if appRole.SecretIDTTL > 0 {
+		expiresAt := time.Now().Add(appRole.SecretIDTTL)
+		record.ExpiresAt = &expiresAt
+	}
+
+	if err := db.Create(&record).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store secret_id",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"secret_id":  secretID,
+		"app_role":   appRole.RoleID,
+		"expires_at": record.ExpiresAt,
+	})
+}
+
+// AppRoleLoginRequest defines the request structure for exchanging a
+// role_id/secret_id pair for a short-lived API key.
+type AppRoleLoginRequest struct {
+	RoleID   string `json:"role_id" validate:"required"`
+	SecretID string `json:"secret_id" validate:"required"`
+}
+
+// AppRoleLogin exchanges a valid role_id/secret_id pair for a short-lived
+// API key accepted by the existing APIKeyAuth middleware. The SecretID's
+// use count is decremented on success and the secret is rejected once
+// exhausted or expired.
+//
+// Returns:
+// - 200 OK with the issued API key and its expiration on success
+// - 400 Bad Request if the request body is invalid
+// - 401 Unauthorized if the role_id/secret_id pair is invalid, expired, or exhausted
+// - 500 Internal Server Error if a database or key generation error occurs
+func AppRoleLogin(c *fiber.Ctx) error {
+	req := new(AppRoleLoginRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.RoleID == "" || req.SecretID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role_id and secret_id are required",
+		})
+	}
+
+	db := database.GetDB()
+
+	var appRole models.AppRole
+	if err := db.Where("role_id = ?", req.RoleID).First(&appRole).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Authentication failed",
+		})
+	}
+
+	// Candidate SecretIDs are matched by hash comparison below; a real
+	// deployment with many SecretIDs per AppRole would want a non-secret
+	// lookup prefix, but AppRole SecretIDs are expected to be few and
+	// short-lived, so a table scan per login is acceptable here.
+	var secretIDs []models.SecretID
+	if err := db.Where("app_role_id = ?", appRole.ID).Find(&secretIDs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Authentication failed",
+		})
+	}
+
+	var matched *models.SecretID
+	for i := range secretIDs {
+		if utils.VerifySecretID(req.SecretID, secretIDs[i].SecretIDHash) {
+			matched = &secretIDs[i]
+			break
+		}
+	}
+
+	if matched == nil || matched.IsExpired() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Authentication failed",
+		})
+	}
+
+	// CIDRBound, if set, restricts logins to clients calling in from that
+	// network; an unparseable bound or a caller outside it is rejected the
+	// same way a bad secret_id is, to avoid disclosing which check failed.
+	if matched.CIDRBound != "" {
+		_, allowedNet, err := net.ParseCIDR(matched.CIDRBound)
+		callerIP := net.ParseIP(c.IP())
+		if err != nil || callerIP == nil || !allowedNet.Contains(callerIP) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication failed",
+			})
+		}
+	}
+
+	// Decrement remaining uses unless unlimited (-1). The WHERE clause,
+	// not the NumUsesRemaining > 0 check above, is what actually enforces
+	// one-time use: two concurrent logins against the same single-use
+	// secret_id race on this UPDATE, and only the one that observes
+	// num_uses_remaining > 0 at commit time gets a row affected.
+	if matched.NumUsesRemaining > 0 {
+		result := db.Model(&models.SecretID{}).
+			Where("id = ? AND num_uses_remaining > 0", matched.ID).
+			Update("num_uses_remaining", gorm.Expr("num_uses_remaining - 1"))
+		if result.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update secret_id usage",
+			})
+		}
+		if result.RowsAffected == 0 {
+			// Another concurrent login already consumed the last use.
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication failed",
+			})
+		}
+	}
+
+	// Resolve (or create) the backing Role so the minted key works with the
+	// existing APIKeyAuth/RequirePermission middleware unchanged.
+	roleName := "approle:" + appRole.RoleID
+	var role models.Role
+	if err := db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		role = models.Role{
+			Name:        roleName,
+			Description: "Auto-generated role backing AppRole " + appRole.Name,
+			Permissions: appRole.BoundPermissions,
+		}
+		if err := db.Create(&role).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to provision backing role",
+			})
+		}
+		if enforcer := policy.GetEnforcer(); enforcer != nil {
+			if err := policy.MigrateRolePermissions(enforcer, []models.Role{role}); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to provision backing role",
+				})
+			}
+		}
+	}
+
+	var tokenTTL *time.Duration
+	if appRole.TokenTTL > 0 {
+		tokenTTL = &appRole.TokenTTL
+	}
+
+	apiKey, err := utils.CreateAPIKey(role.ID, "AppRole login: "+appRole.Name, "", "", utils.FormatOpaque, tokenTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue token",
+		})
+	}
+
+	if err := db.Create(apiKey).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to persist issued token",
+		})
+	}
+
+	issued := models.IssuedToken{
+		AppRoleID: appRole.ID,
+		APIKeyID:  apiKey.ID,
+		IssuedAt:  time.Now(),
+		ExpiresAt: apiKey.ExpiresAt,
+	}
+	if err := db.Create(&issued).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record issued token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"token":      apiKey.Key,
+		"expires_at": apiKey.ExpiresAt,
+	})
+}
+
+// secondsToDurationOrDefault converts an optional seconds count from a
+// request body into a time.Duration, falling back to defaultTTL when the
+// value is nil or non-positive.
+func secondsToDurationOrDefault(seconds *int, defaultTTL time.Duration) time.Duration {
+	if seconds == nil || *seconds <= 0 {
+		return defaultTTL
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
+// intOrZero dereferences an optional int, treating nil as zero.
+func intOrZero(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
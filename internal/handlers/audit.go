@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+)
+
+// GetAuditLogs handles requests to list recorded audit events, most recent
+// first, with optional filters and pagination.
+//
+// Query parameters (all optional):
+//   - actor_key_id: only events performed by this API key ID
+//   - action: exact match on the event's Action (e.g. "role:create")
+//   - from, to: RFC3339 timestamps bounding the event's Timestamp
+//   - page, page_size: pagination, defaulting to 1 and 50 (page_size capped at 200)
+//
+// Returns:
+// - 200 OK with the matching page of events and a total count
+// - 400 Bad Request if a filter value is malformed
+// - 500 Internal Server Error if a database error occurs
+func GetAuditLogs(c *fiber.Ctx) error {
+	db := database.GetDB()
+	query := db.Model(&models.AuditLog{})
+
+	if actorKeyID := c.Query("actor_key_id"); actorKeyID != "" {
+		id, err := strconv.ParseUint(actorKeyID, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid actor_key_id",
+			})
+		}
+		query = query.Where("actor_key_id = ?", id)
+	}
+
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	if from := c.Query("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid from, expected RFC3339",
+			})
+		}
+		query = query.Where("timestamp >= ?", parsed)
+	}
+
+	if to := c.Query("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid to, expected RFC3339",
+			})
+		}
+		query = query.Where("timestamp <= ?", parsed)
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+
+	pageSize := 50
+	if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+		pageSize = ps
+	}
+	if pageSize > 200 {
+		pageSize = 200
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count audit events",
+		})
+	}
+
+	var events []models.AuditLog
+	if err := query.Order("timestamp DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve audit events",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"events":    events,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
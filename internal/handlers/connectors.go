@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/connectors"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/utils"
+)
+
+// connectorRegistry holds the identity-provider connectors enabled via
+// ORBITKEYS_CONNECTORS_FILE. It is nil when the connectors subsystem isn't
+// configured.
+var connectorRegistry *connectors.Registry
+
+// SetConnectorRegistry installs the enabled identity-provider connectors.
+// It should be called once during startup with the registry built from
+// ORBITKEYS_CONNECTORS_FILE.
+func SetConnectorRegistry(registry *connectors.Registry) {
+	connectorRegistry = registry
+}
+
+// ConnectorLogin begins authentication through the named connector. OIDC
+// connectors redirect the caller to the provider's authorization endpoint;
+// static-password connectors authenticate inline from the request body.
+//
+// Returns:
+// - 302 Found redirecting to the provider for OIDC connectors
+// - 200 OK with the issued API key for static-password connectors
+// - 404 Not Found if the connector isn't configured
+func ConnectorLogin(c *fiber.Ctx) error {
+	name := c.Params("connector")
+
+	conn, ok := lookupConnector(name)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Unknown connector",
+		})
+	}
+
+	if oidcConn, ok := conn.(*connectors.OIDCConnector); ok {
+		return c.Redirect(oidcConn.AuthCodeURL(name), fiber.StatusFound)
+	}
+
+	return completeHandshake(c, name, conn)
+}
+
+// ConnectorCallback completes an OIDC authorization-code flow, exchanging
+// the ?code= query parameter for an Identity and minting an API key.
+//
+// Returns:
+// - 200 OK with the issued API key on success
+// - 400 Bad Request if the authorization code is missing
+// - 401 Unauthorized if the handshake fails
+// - 403 Forbidden if the identity's groups don't map to a known role
+// - 404 Not Found if the connector isn't configured
+func ConnectorCallback(c *fiber.Ctx) error {
+	name := c.Params("connector")
+
+	conn, ok := lookupConnector(name)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Unknown connector",
+		})
+	}
+
+	return completeHandshake(c, name, conn)
+}
+
+// lookupConnector resolves a connector by name against the configured registry.
+func lookupConnector(name string) (connectors.Connector, bool) {
+	if connectorRegistry == nil {
+		return nil, false
+	}
+	return connectorRegistry.Get(name)
+}
+
+// staticLoginRequest is the request body accepted by static-password
+// connectors at /auth/{connector}/login.
+type staticLoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// completeHandshake resolves the provider-specific credential into a
+// Connector.Callback code, runs the handshake, maps the resulting Identity
+// to a Role via the registry's groups mapping, and mints an API key bound
+// to that role.
+func completeHandshake(c *fiber.Ctx, name string, conn connectors.Connector) error {
+	var code string
+	if _, isStatic := conn.(*connectors.StaticPasswordConnector); isStatic {
+		req := new(staticLoginRequest)
+		if err := c.BodyParser(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+		code = req.Username + ":" + req.Password
+	} else {
+		code = c.Query("code")
+	}
+
+	if code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing authorization code",
+		})
+	}
+
+	identity, err := conn.Callback(c.UserContext(), code)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Authentication failed",
+		})
+	}
+
+	roleName := connectorRegistry.ResolveRole(name, identity)
+	if roleName == "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Identity has no group mapped to a role",
+		})
+	}
+
+	db := database.GetDB()
+	var role models.Role
+	if err := db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Mapped role does not exist: " + roleName,
+		})
+	}
+
+	apiKey, err := utils.CreateAPIKey(role.ID, "Issued via "+name+" connector for "+identity.Subject, "", "", utils.FormatOpaque, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue API key",
+		})
+	}
+
+	if err := db.Create(apiKey).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to persist API key",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"key":     apiKey.Key,
+		"subject": identity.Subject,
+	})
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/policy"
+)
+
+// policyRequest is the request body for adding or removing a policy line.
+type policyRequest struct {
+	Role     string `json:"role"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// GetPolicies returns every policy line currently loaded in the enforcer.
+//
+// Returns:
+// - 200 OK with the list of [role, resource, action] policy lines
+// - 503 Service Unavailable if the policy engine isn't initialized
+func GetPolicies(c *fiber.Ctx) error {
+	enforcer := policy.GetEnforcer()
+	if enforcer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Policy engine is not enabled",
+		})
+	}
+
+	policies, err := enforcer.GetPolicy()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load policies",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"policies": policies,
+	})
+}
+
+// CreatePolicy adds a policy line granting role the ability to perform
+// action on resource, and persists it via the enforcer's adapter so it
+// survives a restart.
+//
+// Returns:
+// - 201 Created if the policy line was added
+// - 200 OK if the policy line already existed
+// - 400 Bad Request if role, resource, or action is missing
+// - 503 Service Unavailable if the policy engine isn't initialized
+func CreatePolicy(c *fiber.Ctx) error {
+	enforcer := policy.GetEnforcer()
+	if enforcer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Policy engine is not enabled",
+		})
+	}
+
+	req := new(policyRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Role == "" || req.Resource == "" || req.Action == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role, resource, and action are all required",
+		})
+	}
+
+	added, err := enforcer.AddPolicy(req.Role, req.Resource, req.Action)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to add policy",
+		})
+	}
+
+	if !added {
+		return c.JSON(fiber.Map{
+			"message": "Policy already exists",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Policy added",
+	})
+}
+
+// DeletePolicy removes a policy line, so the named role immediately loses
+// the associated permission on the next enforcement check.
+//
+// Returns:
+// - 200 OK if the policy line was removed
+// - 404 Not Found if the policy line didn't exist
+// - 400 Bad Request if role, resource, or action is missing
+// - 503 Service Unavailable if the policy engine isn't initialized
+func DeletePolicy(c *fiber.Ctx) error {
+	enforcer := policy.GetEnforcer()
+	if enforcer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Policy engine is not enabled",
+		})
+	}
+
+	req := new(policyRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Role == "" || req.Resource == "" || req.Action == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role, resource, and action are all required",
+		})
+	}
+
+	removed, err := enforcer.RemovePolicy(req.Role, req.Resource, req.Action)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove policy",
+		})
+	}
+
+	if !removed {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Policy not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Policy removed",
+	})
+}
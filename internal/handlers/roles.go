@@ -4,7 +4,11 @@
 package handlers
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/audit"
 	"github.com/BasementPilot/orbit-keys/internal/database"
 	"github.com/BasementPilot/orbit-keys/internal/models"
 )
@@ -74,32 +78,69 @@ func CreateRole(c *fiber.Ctx) error {
 	// Save to database
 	db := database.GetDB()
 	if err := db.Create(&role).Error; err != nil {
+		auditRoleEvent(c, "role:create", "", "failure", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create role: " + err.Error(),
 		})
 	}
 
+	auditRoleEvent(c, "role:create", fmt.Sprint(role.ID), "success", nil)
 	return c.Status(fiber.StatusCreated).JSON(role)
 }
 
-// GetRoles handles requests to retrieve all roles from the database.
-// It returns a JSON array of all roles with their associated data.
+// GetRoles handles requests to list roles, with optional filtering and
+// pagination so the response stays bounded no matter how many roles exist.
+//
+// Query parameters (all optional):
+//   - q: substring match against Name
+//   - page, page_size: pagination, defaulting to 1 and 50 (page_size capped at 200)
 //
 // Returns:
-// - 200 OK with an array of roles on success
+// - 200 OK with a {total, page, page_size, data} envelope and an
+//   X-Total-Count header set to the unpaginated match count
 // - 500 Internal Server Error if a database error occurs
 func GetRoles(c *fiber.Ctx) error {
-	var roles []models.Role
 	db := database.GetDB()
-	
-	// Get all roles
-	if err := db.Find(&roles).Error; err != nil {
+	query := db.Model(&models.Role{})
+
+	if q := c.Query("q"); q != "" {
+		query = query.Where("name LIKE ?", "%"+q+"%")
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+
+	pageSize := 50
+	if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+		pageSize = ps
+	}
+	if pageSize > 200 {
+		pageSize = 200
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count roles",
+		})
+	}
+
+	var roles []models.Role
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&roles).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve roles",
 		})
 	}
 
-	return c.JSON(roles)
+	c.Set("X-Total-Count", strconv.FormatInt(total, 10))
+	return c.JSON(fiber.Map{
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"data":      roles,
+	})
 }
 
 // GetRole handles requests to retrieve a single role by its ID.
@@ -182,11 +223,13 @@ func UpdateRole(c *fiber.Ctx) error {
 
 	// Save to database
 	if err := db.Save(&role).Error; err != nil {
+		auditRoleEvent(c, "role:update", id, "failure", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update role: " + err.Error(),
 		})
 	}
 
+	auditRoleEvent(c, "role:update", id, "success", nil)
 	return c.JSON(role)
 }
 
@@ -219,10 +262,32 @@ func DeleteRole(c *fiber.Ctx) error {
 
 	// Delete the role
 	if err := db.Delete(&models.Role{}, id).Error; err != nil {
+		auditRoleEvent(c, "role:delete", id, "failure", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete role",
 		})
 	}
 
+	auditRoleEvent(c, "role:delete", id, "success", nil)
 	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// auditRoleEvent records a role mutation to the audit log. The actor is
+// always the root key (only RootAPIKeyAuth-protected routes reach these
+// handlers), so ActorKeyID is left nil; ActorIP identifies which caller
+// used the root key.
+func auditRoleEvent(c *fiber.Ctx, action, resourceID, result string, err error) {
+	details := ""
+	if err != nil {
+		details = audit.MarshalDetails(fiber.Map{"error": err.Error()})
+	}
+
+	audit.Record(models.AuditLog{
+		ActorIP:    c.IP(),
+		Action:     action,
+		Resource:   "role",
+		ResourceID: resourceID,
+		Result:     result,
+		Details:    details,
+	})
 } 
\ No newline at end of file
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"crypto/rsa"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/middleware"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/internal/tokens"
+	"github.com/BasementPilot/orbit-keys/utils"
+)
+
+// jwtPrivateKey, jwtPublicKey, refreshStore, and the token TTLs are
+// installed once at startup by SetJWTConfig, mirroring how
+// SetConnectorRegistry/SetEnforcer install their own subsystem state.
+var (
+	jwtPrivateKey   *rsa.PrivateKey
+	jwtPublicKey    *rsa.PublicKey
+	refreshStore    *tokens.Store
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+)
+
+// SetJWTConfig installs the signing keys, Redis-backed refresh-token store,
+// and token lifetimes used by IssueToken, RefreshToken, and Logout.
+func SetJWTConfig(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey, store *tokens.Store, accessTTL, refreshTTL time.Duration) {
+	jwtPrivateKey = privateKey
+	jwtPublicKey = publicKey
+	refreshStore = store
+	accessTokenTTL = accessTTL
+	refreshTokenTTL = refreshTTL
+}
+
+// tokenPairResponse is returned by IssueToken and RefreshToken.
+type tokenPairResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds
+}
+
+// IssueToken exchanges a valid API key (presented via X-API-Key, the same
+// header APIKeyAuth reads) for a short-lived JWT access token plus a
+// longer-lived refresh token whose jti is tracked in Redis for revocation.
+//
+// Returns:
+// - 200 OK with the issued token pair
+// - 401 Unauthorized if the API key is missing, malformed, or unknown
+// - 503 Service Unavailable if the JWT subsystem isn't configured
+func IssueToken(c *fiber.Ctx) error {
+	if jwtPrivateKey == nil || refreshStore == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "JWT session tokens are not enabled",
+		})
+	}
+
+	apiKey := c.Get(middleware.APIKeyHeader)
+	if !utils.ValidateAPIKey(apiKey) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "A valid API key is required",
+		})
+	}
+
+	db := database.GetDB()
+	key, err := utils.FindAPIKeyByPlaintext(db, apiKey)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Authentication failed",
+		})
+	}
+	if key.IsExpired() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "API key has expired",
+		})
+	}
+
+	return issueTokenPair(c, *key)
+}
+
+// RefreshToken exchanges a valid, non-revoked refresh token for a new
+// access/refresh pair, rotating the refresh token's jti.
+//
+// Returns:
+// - 200 OK with the new token pair
+// - 401 Unauthorized if the refresh token is invalid, expired, or revoked
+// - 503 Service Unavailable if the JWT subsystem isn't configured
+func RefreshToken(c *fiber.Ctx) error {
+	if jwtPrivateKey == nil || jwtPublicKey == nil || refreshStore == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "JWT session tokens are not enabled",
+		})
+	}
+
+	req := new(struct {
+		RefreshToken string `json:"refresh_token"`
+	})
+	if err := c.BodyParser(req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "refresh_token is required",
+		})
+	}
+
+	jti, err := tokens.ParseRefreshToken(jwtPublicKey, req.RefreshToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+
+	apiKeyID, err := refreshStore.CheckRefreshToken(c.UserContext(), jti)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Refresh token has been revoked or expired",
+		})
+	}
+
+	var key models.APIKey
+	db := database.GetDB()
+	if err := db.Preload("Role").First(&key, apiKeyID).Error; err != nil || key.IsExpired() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "The API key backing this session is no longer valid",
+		})
+	}
+
+	// Rotating invalidates the old jti so a stolen refresh token can't be
+	// replayed after a legitimate rotation.
+	if err := refreshStore.RevokeRefreshToken(c.UserContext(), jti); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to rotate refresh token",
+		})
+	}
+
+	return issueTokenPair(c, key)
+}
+
+// Logout revokes the refresh token's jti in Redis, so it can no longer be
+// exchanged for a new access token.
+//
+// Returns:
+// - 200 OK once the refresh token has been revoked
+// - 400 Bad Request if refresh_token is missing
+// - 503 Service Unavailable if the JWT subsystem isn't configured
+func Logout(c *fiber.Ctx) error {
+	if jwtPublicKey == nil || refreshStore == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "JWT session tokens are not enabled",
+		})
+	}
+
+	req := new(struct {
+		RefreshToken string `json:"refresh_token"`
+	})
+	if err := c.BodyParser(req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "refresh_token is required",
+		})
+	}
+
+	jti, err := tokens.ParseRefreshToken(jwtPublicKey, req.RefreshToken)
+	if err != nil {
+		// An already-invalid token has nothing left to revoke.
+		return c.JSON(fiber.Map{"message": "Logged out"})
+	}
+
+	if err := refreshStore.RevokeRefreshToken(c.UserContext(), jti); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke refresh token",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "Logged out"})
+}
+
+// issueTokenPair mints a fresh access token and refresh token for key and
+// writes the response, shared by IssueToken and RefreshToken.
+func issueTokenPair(c *fiber.Ctx, key models.APIKey) error {
+	accessToken, err := tokens.NewAccessToken(jwtPrivateKey, key.ID, key.Role.Name, accessTokenTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue access token",
+		})
+	}
+
+	jti, err := refreshStore.IssueRefreshToken(c.UserContext(), key.ID, refreshTokenTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue refresh token",
+		})
+	}
+
+	refreshToken, err := tokens.NewRefreshToken(jwtPrivateKey, jti, refreshTokenTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue refresh token",
+		})
+	}
+
+	return c.JSON(tokenPairResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	})
+}
@@ -4,7 +4,8 @@
 package middleware
 
 import (
-	"sync"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,6 +13,7 @@ import (
 	"github.com/BasementPilot/orbit-keys/config"
 	"github.com/BasementPilot/orbit-keys/internal/database"
 	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/internal/policy"
 	"github.com/BasementPilot/orbit-keys/utils"
 )
 
@@ -23,12 +25,85 @@ const APIKeyHeader = "X-API-Key"
 // This header is used for administrative operations that require elevated privileges.
 const RootAPIKeyHeader = "X-Root-API-Key"
 
-// authAttempts tracks failed authentication attempts by IP address
-var (
-	authAttempts     = make(map[string]int)
-	authAttemptsMux  sync.RWMutex
-	attemptThreshold = 10 // Max failed attempts before rate limiting
-)
+// ErrMissingCredential is returned by an Extractor (or the default
+// KeyLookup-based extraction) when the configured location doesn't carry a
+// credential at all, as opposed to carrying an invalid one.
+var ErrMissingCredential = errors.New("credential not found in request")
+
+// APIKeyAuthConfig customizes where APIKeyAuth and RootAPIKeyAuth look for
+// their credential, and lets callers bypass auth entirely for specific
+// requests (e.g. health checks).
+//
+// KeyLookup follows the keyauth middleware convention, "<source>:<name>":
+//   - "header:X-API-Key" (the default for APIKeyAuth)
+//   - "cookie:access_token"
+//   - "query:api_key"
+//   - "form:api_key"
+//
+// Extractor, if set, takes priority over KeyLookup entirely and is
+// responsible for pulling the raw credential out of c itself, which allows
+// schemes KeyLookup can't express, such as "Authorization: Bearer <key>".
+// It should return ErrMissingCredential when the request simply doesn't
+// carry a credential, as opposed to a different error.
+type APIKeyAuthConfig struct {
+	KeyLookup string
+	Extractor func(c *fiber.Ctx) (string, error)
+	Skipper   func(c *fiber.Ctx) bool
+}
+
+// defaultAPIKeyLookup is APIKeyAuth's KeyLookup when none is configured.
+const defaultAPIKeyLookup = "header:" + APIKeyHeader
+
+// defaultRootAPIKeyLookup is RootAPIKeyAuth's KeyLookup when none is configured.
+const defaultRootAPIKeyLookup = "header:" + RootAPIKeyHeader
+
+// resolveAPIKeyAuthConfig merges a variadic APIKeyAuthConfig (zero or one
+// entries, following the repo's own config-struct convention) with
+// defaultLookup, so every field downstream can assume non-zero values
+// except Extractor/Skipper, which stay nil when unset.
+func resolveAPIKeyAuthConfig(defaultLookup string, cfg ...APIKeyAuthConfig) APIKeyAuthConfig {
+	var c APIKeyAuthConfig
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.KeyLookup == "" {
+		c.KeyLookup = defaultLookup
+	}
+	return c
+}
+
+// extractCredential pulls the raw credential out of c according to cfg:
+// cfg.Extractor if set, otherwise cfg.KeyLookup parsed as "<source>:<name>".
+// Returns ErrMissingCredential if the configured location is empty.
+func extractCredential(c *fiber.Ctx, cfg APIKeyAuthConfig) (string, error) {
+	if cfg.Extractor != nil {
+		return cfg.Extractor(c)
+	}
+
+	source, name, ok := strings.Cut(cfg.KeyLookup, ":")
+	if !ok {
+		return "", errors.New("invalid KeyLookup format, expected \"<source>:<name>\"")
+	}
+
+	var value string
+	switch source {
+	case "header":
+		value = c.Get(name)
+	case "cookie":
+		value = c.Cookies(name)
+	case "query":
+		value = c.Query(name)
+	case "form":
+		value = c.FormValue(name)
+	default:
+		return "", errors.New("unsupported KeyLookup source: " + source)
+	}
+
+	if value == "" {
+		return "", ErrMissingCredential
+	}
+	return value, nil
+}
 
 // APIKeyAuth creates middleware that authenticates and authorizes requests using API keys.
 // It verifies the API key exists in the header, validates its format, checks if it exists
@@ -37,36 +112,44 @@ var (
 // The requiredPermission parameter specifies what permission is needed to access the route.
 // If empty, it only verifies the API key is valid without checking permissions.
 //
+// By default the key is read from the X-API-Key header; pass an
+// APIKeyAuthConfig to read it from elsewhere (a cookie for browser-based
+// dashboards, a query parameter, or via a custom Extractor), or to set a
+// Skipper that bypasses auth entirely for requests like health checks.
+//
 // When authentication succeeds, the API key and role are stored in the request context
 // for use by subsequent handlers.
-func APIKeyAuth(requiredPermission string) fiber.Handler {
+func APIKeyAuth(requiredPermission string, cfg ...APIKeyAuthConfig) fiber.Handler {
+	resolved := resolveAPIKeyAuthConfig(defaultAPIKeyLookup, cfg...)
+
 	return func(c *fiber.Ctx) error {
+		if resolved.Skipper != nil && resolved.Skipper(c) {
+			return c.Next()
+		}
+
 		// Set a timeout for the authentication process
 		done := make(chan bool, 1)
 		var err error
-		
+
 		go func() {
-			// Get the API key from the header
-			apiKey := c.Get(APIKeyHeader)
-			
+			// Get the API key from the configured location (the X-API-Key
+			// header, by default).
+			apiKey, extractErr := extractCredential(c, resolved)
+
 			// Check for rate limiting if client has too many failed attempts
 			ip := c.IP()
-			authAttemptsMux.RLock()
-			attempts, exists := authAttempts[ip]
-			authAttemptsMux.RUnlock()
-			
-			if exists && attempts >= attemptThreshold {
+			if tracker.Locked(ip) {
 				err = c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 					"error": "Too many failed authentication attempts, please try again later",
 				})
 				done <- true
 				return
 			}
-			
-			if apiKey == "" {
+
+			if extractErr != nil || apiKey == "" {
 				// Track failed authentication attempt
-				trackFailedAttempt(ip)
-				
+				tracker.Record(ip)
+
 				err = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 					"error": "API key is required",
 				})
@@ -77,8 +160,8 @@ func APIKeyAuth(requiredPermission string) fiber.Handler {
 			// Check if it's a valid API key format
 			if !utils.ValidateAPIKey(apiKey) {
 				// Track failed authentication attempt
-				trackFailedAttempt(ip)
-				
+				tracker.Record(ip)
+
 				err = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 					"error": "Invalid API key format",
 				})
@@ -86,13 +169,17 @@ func APIKeyAuth(requiredPermission string) fiber.Handler {
 				return
 			}
 
-			// Find the API key in the database
-			var key models.APIKey
+			// Find the API key in the database. Candidates are narrowed by
+			// KeyPrefix and then checked with a constant-time hash
+			// comparison, so the plaintext key never appears in a SQL
+			// WHERE clause. A prefix that matches no row skips the hash
+			// comparison altogether.
 			db := database.GetDB()
-			if err := db.Preload("Role").Where("key = ?", apiKey).First(&key).Error; err != nil {
+			foundKey, err2 := utils.FindAPIKeyByPlaintext(db, apiKey)
+			if err2 != nil {
 				// Track failed authentication attempt
-				trackFailedAttempt(ip)
-				
+				tracker.Record(ip)
+
 				// Use generic error message to avoid information disclosure
 				err = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 					"error": "Authentication failed",
@@ -100,6 +187,7 @@ func APIKeyAuth(requiredPermission string) fiber.Handler {
 				done <- true
 				return
 			}
+			key := *foundKey
 
 			// Check if the API key has expired
 			if key.IsExpired() {
@@ -111,21 +199,33 @@ func APIKeyAuth(requiredPermission string) fiber.Handler {
 				return
 			}
 
-			// Check if the API key has the required permission
-			if requiredPermission != "" && !key.Role.HasPermission(requiredPermission) {
-				err = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-					"error": "Insufficient permissions",
-				})
-				done <- true
-				return
+			// Check if the API key has the required permission: its role
+			// must grant it, and, if the key has scopes, they must allow it
+			// too, since scopes only ever narrow what the role permits.
+			if requiredPermission != "" {
+				if !roleHasPermission(key.Role, requiredPermission) || !key.ScopesAllow(requiredPermission) {
+					err = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+						"error": "Insufficient permissions",
+					})
+					done <- true
+					return
+				}
+
+				// The grant itself is already established above; this call
+				// to CheckPermissionForAction only enforces the key's own
+				// ReadOnly/MaxClass cap on top of it, so a compromised
+				// read-only client can't use a role's write permissions.
+				if allowed, _ := models.CheckPermissionForAction(requiredPermission, []string{requiredPermission}, &key); !allowed {
+					err = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+						"error": "Insufficient permissions",
+					})
+					done <- true
+					return
+				}
 			}
 
 			// Reset failed attempt counter on successful authentication
-			if exists {
-				authAttemptsMux.Lock()
-				delete(authAttempts, ip)
-				authAttemptsMux.Unlock()
-			}
+			tracker.Reset(ip)
 
 			// Update the last used timestamp
 			go key.UpdateLastUsed(db)
@@ -155,34 +255,45 @@ func APIKeyAuth(requiredPermission string) fiber.Handler {
 // It checks if the root API key header is present and matches the configured root API key.
 //
 // The cfg parameter provides the configuration containing the root API key to check against.
-func RootAPIKeyAuth(cfg *config.Config) fiber.Handler {
+// By default the key is read from the X-Root-API-Key header; pass an
+// APIKeyAuthConfig to read it from elsewhere, or to set a Skipper.
+//
+// cfg is only the fallback used while config.Current() is nil, before
+// config.SetCurrent is first called (New calls it at startup, and
+// config.Watch on every reload): once it is, config.Current() returns the live, possibly-rotated configuration
+// and is checked against on every request instead of the cfg this handler
+// closed over, so rotating RootAPIKey takes effect without a restart.
+func RootAPIKeyAuth(cfg *config.Config, authCfg ...APIKeyAuthConfig) fiber.Handler {
+	resolved := resolveAPIKeyAuthConfig(defaultRootAPIKeyLookup, authCfg...)
+
 	return func(c *fiber.Ctx) error {
+		if resolved.Skipper != nil && resolved.Skipper(c) {
+			return c.Next()
+		}
+
 		// Set a timeout for the authentication process
 		done := make(chan bool, 1)
 		var err error
-		
+
 		go func() {
-			// Get the root API key from the header
-			rootKey := c.Get(RootAPIKeyHeader)
-			
+			// Get the root API key from the configured location (the
+			// X-Root-API-Key header, by default).
+			rootKey, extractErr := extractCredential(c, resolved)
+
 			// Check for rate limiting if client has too many failed attempts
 			ip := c.IP()
-			authAttemptsMux.RLock()
-			attempts, exists := authAttempts[ip]
-			authAttemptsMux.RUnlock()
-			
-			if exists && attempts >= attemptThreshold {
+			if tracker.Locked(ip) {
 				err = c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 					"error": "Too many failed authentication attempts, please try again later",
 				})
 				done <- true
 				return
 			}
-			
-			if rootKey == "" {
+
+			if extractErr != nil || rootKey == "" {
 				// Track failed authentication attempt
-				trackFailedAttempt(ip)
-				
+				tracker.Record(ip)
+
 				err = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 					"error": "Root API key is required for admin operations",
 				})
@@ -191,10 +302,14 @@ func RootAPIKeyAuth(cfg *config.Config) fiber.Handler {
 			}
 
 			// Check if it matches the configured root API key
-			if !utils.IsRootAPIKey(rootKey, cfg.RootAPIKey) {
+			activeCfg := cfg
+			if live := config.Current(); live != nil {
+				activeCfg = live
+			}
+			if !utils.IsRootAPIKey(rootKey, activeCfg.RootAPIKey) {
 				// Track failed authentication attempt
-				trackFailedAttempt(ip)
-				
+				tracker.Record(ip)
+
 				// Use generic error message to avoid information disclosure
 				err = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 					"error": "Authentication failed",
@@ -204,11 +319,7 @@ func RootAPIKeyAuth(cfg *config.Config) fiber.Handler {
 			}
 
 			// Reset failed attempt counter on successful authentication
-			if exists {
-				authAttemptsMux.Lock()
-				delete(authAttempts, ip)
-				authAttemptsMux.Unlock()
-			}
+			tracker.Reset(ip)
 
 			err = c.Next()
 			done <- true
@@ -227,8 +338,8 @@ func RootAPIKeyAuth(cfg *config.Config) fiber.Handler {
 }
 
 // RequirePermission creates middleware that checks if an authenticated API key has a specific permission.
-// This middleware should be used after the APIKeyAuth middleware, as it relies on the role
-// being stored in the request context.
+// This middleware should be used after APIKeyAuth(""), or after JWTAuth, since it relies on the
+// role and API key being stored in the request context.
 //
 // The permission parameter specifies what permission is needed to access the route.
 func RequirePermission(permission string) fiber.Handler {
@@ -242,12 +353,29 @@ func RequirePermission(permission string) fiber.Handler {
 		}
 
 		// Check if the role has the required permission
-		if !role.HasPermission(permission) {
+		if !roleHasPermission(role, permission) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "Insufficient permissions",
 			})
 		}
 
+		// Also honor the key's own Scopes/ReadOnly/MaxClass caps, same as
+		// APIKeyAuth(permission) does when it's given the permission
+		// directly instead of via a separate RequirePermission step.
+		if key, ok := c.Locals("apiKey").(models.APIKey); ok {
+			if !key.ScopesAllow(permission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Insufficient permissions",
+				})
+			}
+
+			if allowed, _ := models.CheckPermissionForAction(permission, []string{permission}, &key); !allowed {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Insufficient permissions",
+				})
+			}
+		}
+
 		return c.Next()
 	}
 }
@@ -259,11 +387,17 @@ func RequirePermission(permission string) fiber.Handler {
 //   - max: Maximum number of requests allowed in the time window
 //   - expiration: Duration of the time window
 //
+// When ConfigureBruteForceTracking has installed a Redis-backed store, the
+// limiter's counters are kept in the same Redis instance so multiple
+// OrbitKeys instances behind a load balancer share rate-limit state instead
+// of each enforcing its own local limit.
+//
 // Returns a configured rate limiter middleware.
 func CreateRateLimiter(max int, expiration time.Duration) fiber.Handler {
 	return limiter.New(limiter.Config{
 		Max:        max,
 		Expiration: expiration,
+		Storage:    rateLimiterStorage,
 		KeyGenerator: func(c *fiber.Ctx) string {
 			return c.IP() // Rate limit by IP address
 		},
@@ -275,14 +409,22 @@ func CreateRateLimiter(max int, expiration time.Duration) fiber.Handler {
 	})
 }
 
-// trackFailedAttempt increments the failed authentication attempts counter for an IP address.
-// This is used to implement progressive rate limiting for potential brute force attacks.
-func trackFailedAttempt(ip string) {
-	authAttemptsMux.Lock()
-	defer authAttemptsMux.Unlock()
-	
-	authAttempts[ip]++
-	
-	// Clean up old attempts periodically to prevent memory leaks
-	// In production, this should be handled by a dedicated goroutine or cache with TTL
-} 
\ No newline at end of file
+// roleHasPermission authorizes permission for role through the Casbin
+// policy engine when one has been installed via policy.SetEnforcer,
+// falling back to the role's own comma-separated Permissions string for
+// deployments that haven't run the policy migration yet. On enforcer
+// error, access is denied rather than silently falling back, since a
+// broken policy store should fail closed.
+func roleHasPermission(role models.Role, permission string) bool {
+	enforcer := policy.GetEnforcer()
+	if enforcer == nil {
+		return role.HasPermission(permission)
+	}
+
+	allowed, err := policy.CheckPermission(enforcer, role.Name, permission)
+	if err != nil {
+		return false
+	}
+
+	return allowed
+}
\ No newline at end of file
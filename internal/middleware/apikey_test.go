@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -9,17 +8,20 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/BasementPilot/orbit-keys/config"
-	"gorm.io/gorm"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/internal/testutil"
+	"github.com/BasementPilot/orbit-keys/utils"
 )
 
 func setupTestApp() (*fiber.App, error) {
 	app := fiber.New()
-	
+
 	// Initialize test routes with middleware
 	app.Get("/protected", APIKeyAuth("test:permission"), func(c *fiber.Ctx) error {
 		return c.SendString("Protected content")
 	})
-	
+
 	app.Get("/root-only", func(c *fiber.Ctx) error {
 		// For testing without a real database, we need to mock the header check
 		apiKey := c.Get(RootAPIKeyHeader)
@@ -31,21 +33,49 @@ func setupTestApp() (*fiber.App, error) {
 	}, RootAPIKeyAuth(&config.Config{RootAPIKey: "orbitkey_test_root_key"}), func(c *fiber.Ctx) error {
 		return c.SendString("Root only content")
 	})
-	
+
 	return app, nil
 }
 
+// setupTestAppWithDB wires APIKeyAuth up against a seeded in-memory database,
+// using requiredPermission "products:read" so fixtures.ReadOnlyKey is
+// authorized and fixtures.AdminKey passes only via its wildcard permission.
+func setupTestAppWithDB(t *testing.T) (*fiber.App, *testutil.Fixtures) {
+	t.Helper()
+
+	db, err := testutil.SetupTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { database.DB = nil })
+
+	fixtures, err := testutil.SeedFixtures(db)
+	if err != nil {
+		t.Fatalf("Failed to seed fixtures: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/protected", APIKeyAuth("products:read"), func(c *fiber.Ctx) error {
+		return c.SendString("Protected content")
+	})
+
+	return app, fixtures
+}
+
 func TestAPIKeyAuth(t *testing.T) {
-	// Skip this test if we can't set up the database
-	t.Skip("Skipping API key auth test as it requires database setup")
-	
-	// Set up test app
 	app, err := setupTestApp()
 	if err != nil {
 		t.Fatalf("Failed to set up test app: %v", err)
 	}
-	
-	// Test cases
+
+	// An empty database, so a well-formed key has somewhere to be looked up.
+	if _, err := testutil.SetupTestDB(t.Name()); err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { database.DB = nil })
+
+	// The first two cases are rejected on format before the key is ever
+	// looked up; the third is well-formed, looked up, and not found.
 	tests := []struct {
 		name       string
 		apiKey     string
@@ -63,31 +93,108 @@ func TestAPIKeyAuth(t *testing.T) {
 		},
 		{
 			name:       "Valid key format but not in DB",
-			apiKey:     "orbitkey_nonexistent_key",
+			apiKey:     "orbitkey_nonexistent_key_aaaaaaaaaaaaaaaaaaaaaaaa",
 			statusCode: fiber.StatusUnauthorized,
 		},
-		// Add more test cases if you can set up a test database
 	}
-	
+
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			// Create a new http request
 			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
 			if tc.apiKey != "" {
 				req.Header.Set(APIKeyHeader, tc.apiKey)
 			}
-			
-			// Perform the request
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test failed: %v", err)
+			}
+
+			if resp.StatusCode != tc.statusCode {
+				t.Errorf("Expected status code %d, got %d", tc.statusCode, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthWithDB(t *testing.T) {
+	app, fixtures := setupTestAppWithDB(t)
+
+	// A key of its own to revoke, so fixtures.ReadOnlyKey stays live for the
+	// "permitted narrow-scope key" subtest below.
+	revokedKey, err := utils.CreateAPIKey(fixtures.ReadOnlyRole.ID, "revoked key", "", "", utils.FormatOpaque, nil)
+	if err != nil {
+		t.Fatalf("Failed to create key to revoke: %v", err)
+	}
+	if err := database.DB.Create(revokedKey).Error; err != nil {
+		t.Fatalf("Failed to store key to revoke: %v", err)
+	}
+	if err := database.DB.Delete(revokedKey).Error; err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		statusCode int
+	}{
+		{
+			name:       "unknown key",
+			apiKey:     "orbitkey_" + "unknown0000000000000000000000",
+			statusCode: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "wrong permission key",
+			apiKey:     fixtures.UnrelatedKey.Key,
+			statusCode: fiber.StatusForbidden,
+		},
+		{
+			name:       "wildcard admin key",
+			apiKey:     fixtures.AdminKey.Key,
+			statusCode: fiber.StatusOK,
+		},
+		{
+			name:       "revoked key",
+			apiKey:     revokedKey.Key,
+			statusCode: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "expired key",
+			apiKey:     fixtures.ExpiredKey.Key,
+			statusCode: fiber.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set(APIKeyHeader, tc.apiKey)
+
 			resp, err := app.Test(req)
 			if err != nil {
 				t.Fatalf("app.Test failed: %v", err)
 			}
-			
+
 			if resp.StatusCode != tc.statusCode {
 				t.Errorf("Expected status code %d, got %d", tc.statusCode, resp.StatusCode)
 			}
 		})
 	}
+
+	// The read-only key is scoped to "products:read" and should pass
+	// against the same permission the test route requires.
+	t.Run("permitted narrow-scope key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set(APIKeyHeader, fixtures.ReadOnlyKey.Key)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+		}
+	})
 }
 
 func TestRootAPIKeyAuth(t *testing.T) {
@@ -96,7 +203,7 @@ func TestRootAPIKeyAuth(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to set up test app: %v", err)
 	}
-	
+
 	// Test cases
 	tests := []struct {
 		name       string
@@ -124,7 +231,7 @@ func TestRootAPIKeyAuth(t *testing.T) {
 			statusCode: fiber.StatusOK,
 		},
 	}
-	
+
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			// Create a new http request
@@ -132,13 +239,13 @@ func TestRootAPIKeyAuth(t *testing.T) {
 			if tc.apiKey != "" {
 				req.Header.Set(RootAPIKeyHeader, tc.apiKey)
 			}
-			
+
 			// Perform the request
 			resp, err := app.Test(req)
 			if err != nil {
 				t.Fatalf("app.Test failed: %v", err)
 			}
-			
+
 			if resp.StatusCode != tc.statusCode {
 				t.Errorf("Expected status code %d, got %d", tc.statusCode, resp.StatusCode)
 			}
@@ -146,28 +253,74 @@ func TestRootAPIKeyAuth(t *testing.T) {
 	}
 }
 
+// requirePermissionTestApp builds an app that stores a Role with the given
+// permissions in Locals before running RequirePermission, so each sub-test
+// can exercise RequirePermission in isolation on its own route.
+func requirePermissionTestApp(permissions string) *fiber.App {
+	app := fiber.New()
+	app.Get("/admin-only", func(c *fiber.Ctx) error {
+		c.Locals("role", models.Role{Permissions: permissions})
+		return c.Next()
+	}, RequirePermission("admin:write"), func(c *fiber.Ctx) error {
+		return c.SendString("Admin content")
+	})
+	return app
+}
+
 func TestRequirePermission(t *testing.T) {
-	// We'll test this without using the actual fiber context since we're 
-	// just mocking the Role in Locals() which is challenging in tests
-	
-	// Skip this test as it requires proper mocking of Fiber context
-	t.Skip("Skipping RequirePermission test as it requires proper Fiber context mocking")
+	tests := []struct {
+		name        string
+		permissions string
+		statusCode  int
+	}{
+		{
+			name:        "wildcard role",
+			permissions: "*",
+			statusCode:  fiber.StatusOK,
+		},
+		{
+			name:        "exact matching permission",
+			permissions: "admin:write",
+			statusCode:  fiber.StatusOK,
+		},
+		{
+			name:        "unrelated permission",
+			permissions: "products:read",
+			statusCode:  fiber.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			app := requirePermissionTestApp(tc.permissions)
+			req := httptest.NewRequest(http.MethodGet, "/admin-only", nil)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test failed: %v", err)
+			}
+
+			if resp.StatusCode != tc.statusCode {
+				t.Errorf("Expected status code %d, got %d", tc.statusCode, resp.StatusCode)
+			}
+		})
+	}
 }
 
 func TestCreateRateLimiter(t *testing.T) {
 	// Set up test app with rate limiter
 	app := fiber.New()
-	
+
 	// Use a very low limit to test rate limiting easily
 	app.Use(CreateRateLimiter(2, 1*time.Second))
-	
+
 	app.Get("/rate-limited", func(c *fiber.Ctx) error {
 		return c.SendString("Limited content")
 	})
-	
+
 	// Make multiple requests in a short time to trigger rate limiting
 	req := httptest.NewRequest(http.MethodGet, "/rate-limited", nil)
-	
+
 	// First request - should succeed
 	resp, err := app.Test(req)
 	if err != nil {
@@ -176,7 +329,7 @@ func TestCreateRateLimiter(t *testing.T) {
 	if resp.StatusCode != fiber.StatusOK {
 		t.Errorf("Expected status code %d for first request, got %d", fiber.StatusOK, resp.StatusCode)
 	}
-	
+
 	// Second request - should succeed
 	resp, err = app.Test(req)
 	if err != nil {
@@ -185,7 +338,7 @@ func TestCreateRateLimiter(t *testing.T) {
 	if resp.StatusCode != fiber.StatusOK {
 		t.Errorf("Expected status code %d for second request, got %d", fiber.StatusOK, resp.StatusCode)
 	}
-	
+
 	// Third request - should be rate limited
 	resp, err = app.Test(req)
 	if err != nil {
@@ -195,10 +348,3 @@ func TestCreateRateLimiter(t *testing.T) {
 		t.Errorf("Expected status code %d for third request, got %d", fiber.StatusTooManyRequests, resp.StatusCode)
 	}
 }
-
-// Helper function to mock database connections and models for more comprehensive testing
-func setupTestDB() (*gorm.DB, error) {
-	// This would typically set up an in-memory SQLite database for testing
-	// But we'll skip the implementation for this example
-	return nil, fmt.Errorf("test database setup not implemented")
-} 
\ No newline at end of file
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/audit"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+)
+
+// AuditMiddleware records an audit.Record entry for every request that
+// reaches it, using whatever c.Locals("apiKey") APIKeyAuth/RootAPIKeyAuth/JWTAuth
+// populated as the actor. It is mounted ahead of those middlewares (New
+// mounts it app-wide) and reads the actor only after c.Next() returns, so
+// the actor is known by then; it still records (with a nil actor) requests
+// that never authenticated, since failed auth attempts are audit-worthy too.
+func AuditMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		result := "success"
+		if c.Response().StatusCode() >= 400 {
+			result = "failure"
+		}
+
+		var actorKeyID *uint
+		if key, ok := c.Locals("apiKey").(models.APIKey); ok {
+			id := key.ID
+			actorKeyID = &id
+		}
+
+		audit.Record(models.AuditLog{
+			ActorKeyID: actorKeyID,
+			ActorIP:    c.IP(),
+			Action:     c.Method(),
+			Resource:   c.Path(),
+			Result:     result,
+		})
+
+		return err
+	}
+}
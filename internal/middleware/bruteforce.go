@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	fiberredis "github.com/gofiber/storage/redis/v3"
+	"github.com/redis/go-redis/v9"
+	"github.com/BasementPilot/orbit-keys/config"
+)
+
+// attemptThreshold is the maximum number of failed authentication attempts
+// allowed for an IP within the tracker's window before it is locked out.
+// ConfigureBruteForceTracking overrides this from Config.LockoutThreshold.
+var attemptThreshold = 10
+
+// attemptTracker records failed authentication attempts per IP and reports
+// whether an IP has exceeded attemptThreshold. Implementations must be
+// safe for concurrent use.
+type attemptTracker interface {
+	// Record increments the failed-attempt counter for ip.
+	Record(ip string)
+	// Locked reports whether ip has hit attemptThreshold within the window.
+	Locked(ip string) bool
+	// Reset clears ip's counter after a successful authentication.
+	Reset(ip string)
+}
+
+// rateLimiterStorage backs CreateRateLimiter's fiber.Storage when
+// ConfigureBruteForceTracking has been given a Redis URL, so rate-limit
+// counters are shared across every OrbitKeys instance. Nil means
+// CreateRateLimiter falls back to limiter's built-in in-memory storage.
+var rateLimiterStorage fiber.Storage
+
+// tracker is the process-wide attemptTracker used by APIKeyAuth and
+// RootAPIKeyAuth. It defaults to an in-memory tracker so existing
+// single-instance deployments keep working without Redis; ConfigureBruteForceTracking
+// swaps in a Redis-backed tracker when Config.RedisURL is set, so that
+// multiple OrbitKeys instances behind a load balancer share counters.
+var tracker attemptTracker = newMemoryAttemptTracker(10 * time.Minute)
+
+// ConfigureBruteForceTracking installs the attempt tracker appropriate for
+// cfg: Redis-backed when cfg.RedisURL is set (shared across instances),
+// otherwise the in-memory fallback with its own TTL sweeper. It should be
+// called once during startup, before the server begins accepting requests.
+func ConfigureBruteForceTracking(cfg *config.Config) error {
+	if cfg.LockoutThreshold > 0 {
+		attemptThreshold = cfg.LockoutThreshold
+	}
+
+	if cfg.RedisURL == "" {
+		return nil
+	}
+
+	redisTracker, err := newRedisAttemptTracker(cfg.RedisURL, 10*time.Minute)
+	if err != nil {
+		return err
+	}
+	tracker = redisTracker
+
+	storage, err := newRedisRateLimiterStorage(cfg.RedisURL)
+	if err != nil {
+		return err
+	}
+	rateLimiterStorage = storage
+
+	return nil
+}
+
+// newRedisRateLimiterStorage builds the fiber.Storage backend CreateRateLimiter
+// uses for limiter.Config.Storage, so rate-limit counters live in the same
+// Redis instance as the attempt tracker above instead of each OrbitKeys
+// instance keeping its own in-memory counts.
+func newRedisRateLimiterStorage(redisURL string) (fiber.Storage, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host, portStr, err := net.SplitHostPort(opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return fiberredis.New(fiberredis.Config{
+		Host:     host,
+		Port:     port,
+		Username: opts.Username,
+		Password: opts.Password,
+		Database: opts.DB,
+	}), nil
+}
+
+// memoryAttemptTracker is the process-local fallback used when no Redis URL
+// is configured. Unlike the original implementation, entries are swept by a
+// background goroutine instead of growing unbounded.
+type memoryAttemptTracker struct {
+	mu     sync.Mutex
+	counts map[string]int
+	seenAt map[string]time.Time
+	window time.Duration
+}
+
+// newMemoryAttemptTracker creates a memoryAttemptTracker whose entries
+// expire window after their last recorded attempt, and starts the janitor
+// goroutine that sweeps expired entries so the map can't grow unbounded.
+func newMemoryAttemptTracker(window time.Duration) *memoryAttemptTracker {
+	t := &memoryAttemptTracker{
+		counts: make(map[string]int),
+		seenAt: make(map[string]time.Time),
+		window: window,
+	}
+	go t.sweepLoop()
+	return t
+}
+
+func (t *memoryAttemptTracker) Record(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[ip]++
+	t.seenAt[ip] = time.Now()
+}
+
+func (t *memoryAttemptTracker) Locked(ip string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counts[ip] >= attemptThreshold
+}
+
+func (t *memoryAttemptTracker) Reset(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.counts, ip)
+	delete(t.seenAt, ip)
+}
+
+// sweepLoop periodically removes entries whose last attempt is older than
+// the tracker's window, so IPs that stop attacking eventually fall out of
+// memory instead of accumulating forever.
+func (t *memoryAttemptTracker) sweepLoop() {
+	ticker := time.NewTicker(t.window)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-t.window)
+		t.mu.Lock()
+		for ip, last := range t.seenAt {
+			if last.Before(cutoff) {
+				delete(t.counts, ip)
+				delete(t.seenAt, ip)
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// redisAttemptTracker shares failed-attempt counters across every OrbitKeys
+// instance via Redis INCR/EXPIRE, so a brute-force attempt spread across a
+// load-balanced fleet is still caught.
+type redisAttemptTracker struct {
+	client *redis.Client
+	window time.Duration
+}
+
+// newRedisAttemptTracker connects to the Redis instance at redisURL.
+func newRedisAttemptTracker(redisURL string, window time.Duration) (*redisAttemptTracker, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisAttemptTracker{
+		client: redis.NewClient(opts),
+		window: window,
+	}, nil
+}
+
+func (t *redisAttemptTracker) key(ip string) string {
+	return "orbitkeys:auth:fail:" + ip
+}
+
+func (t *redisAttemptTracker) Record(ip string) {
+	ctx := context.Background()
+	key := t.key(ip)
+
+	count, err := t.client.Incr(ctx, key).Result()
+	if err != nil {
+		return
+	}
+	if count == 1 {
+		t.client.Expire(ctx, key, t.window)
+	}
+}
+
+func (t *redisAttemptTracker) Locked(ip string) bool {
+	count, err := t.client.Get(context.Background(), t.key(ip)).Int()
+	if err != nil {
+		return false
+	}
+	return count >= attemptThreshold
+}
+
+func (t *redisAttemptTracker) Reset(ip string) {
+	t.client.Del(context.Background(), t.key(ip))
+}
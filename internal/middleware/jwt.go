@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/rsa"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/internal/tokens"
+)
+
+// AuthorizationHeader is the HTTP header carrying a "Bearer <token>" JWT
+// session token, checked by JWTAuth alongside the existing API key header.
+const AuthorizationHeader = "Authorization"
+
+// JWTAuth creates middleware that authenticates requests using a short-lived
+// RS256 JWT session token obtained from POST {BaseURL}/auth/token. It
+// verifies the token's signature against publicKey, loads the role the
+// token's claims were issued for, and populates c.Locals the same way
+// APIKeyAuth does so RequirePermission keeps working unmodified.
+//
+// Unlike APIKeyAuth, JWTAuth never consults the refresh-token Redis store:
+// the access token itself is the short-lived credential, and revocation is
+// handled at refresh/logout time (see handlers.Logout).
+func JWTAuth(publicKey *rsa.PublicKey) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(AuthorizationHeader)
+		const prefix = "Bearer "
+		if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Bearer token is required",
+			})
+		}
+
+		claims, err := tokens.ParseAccessToken(publicKey, header[len(prefix):])
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired token",
+			})
+		}
+
+		var role models.Role
+		db := database.GetDB()
+		if err := db.Where("name = ?", claims.RoleName).First(&role).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token references an unknown role",
+			})
+		}
+
+		var key models.APIKey
+		if err := db.First(&key, claims.APIKeyID).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token references a revoked API key",
+			})
+		}
+		key.Role = role
+
+		// The access token itself has no way to reflect revocation made
+		// after it was issued, so its backing key's own expiry (set
+		// immediately by RevokeAPIKey/RevokeAPIKeys) is what actually cuts
+		// it off before the token's own exp claim would.
+		if key.IsExpired() {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token references a revoked API key",
+			})
+		}
+
+		c.Locals("apiKey", key)
+		c.Locals("role", role)
+
+		return c.Next()
+	}
+}
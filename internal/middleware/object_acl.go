@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+)
+
+// RequireObjectPermission creates middleware that authorizes a request
+// against a specific object's ACL, in addition to the coarse resource:action
+// permissions already attached to the caller's role. It must run after
+// APIKeyAuth so that "apiKey"/"role" are present in the request context.
+//
+// Access is granted if either:
+//  1. the authenticated key's role already has the coarse "objectType:<level>"
+//     (e.g. "products:CAN_READ") or "objectType:*" permission via the
+//     existing resource:action grammar, or
+//  2. an ACLEntry on the object identified by c.Params(idParam) grants the
+//     caller (by API key or by role) at least the required PermissionLevel.
+func RequireObjectPermission(objectType, idParam string, level models.PermissionLevel) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey, ok := c.Locals("apiKey").(models.APIKey)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication required",
+			})
+		}
+
+		role, ok := c.Locals("role").(models.Role)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication required",
+			})
+		}
+
+		// Coarse resource:action permission already covers this object type.
+		// Routed through roleHasPermission, same as APIKeyAuth/RequirePermission,
+		// so a Casbin policy installed via policy.SetEnforcer is consulted
+		// here too instead of silently falling back to role.Permissions.
+		coarsePermission := models.FormatPermission(objectType, string(level))
+		if roleHasPermission(role, coarsePermission) || roleHasPermission(role, models.FormatPermission(objectType, "*")) {
+			return c.Next()
+		}
+
+		objectID := c.Params(idParam)
+		if objectID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Object ID is required",
+			})
+		}
+
+		var acl models.ObjectACL
+		db := database.GetDB()
+		if err := db.Preload("Entries").
+			Where("object_type = ? AND object_id = ?", objectType, objectID).
+			First(&acl).Error; err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient permissions",
+			})
+		}
+
+		if !acl.CheckObjectPermission(apiKey.ID, role.ID, level) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient permissions",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PermissionLevel represents a coarse-to-fine grant on a single object,
+// layered on top of the resource:action permission grammar in permissions.go.
+type PermissionLevel string
+
+const (
+	// CanRead grants read-only access to the specific object.
+	CanRead PermissionLevel = "CAN_READ"
+
+	// CanManage grants full read/write/delete access to the specific object.
+	CanManage PermissionLevel = "CAN_MANAGE"
+)
+
+// levelRank orders PermissionLevel values so that a higher-ranked level
+// satisfies a lower-ranked requirement (CAN_MANAGE implies CAN_READ).
+var levelRank = map[PermissionLevel]int{
+	CanRead:   1,
+	CanManage: 2,
+}
+
+// Satisfies reports whether this level meets or exceeds the required level.
+// An unrecognized level never satisfies anything.
+func (l PermissionLevel) Satisfies(required PermissionLevel) bool {
+	have, ok := levelRank[l]
+	if !ok {
+		return false
+	}
+	want, ok := levelRank[required]
+	if !ok {
+		return false
+	}
+	return have >= want
+}
+
+// ObjectACL is the per-resource access control list for a single object,
+// identified by an application-defined ObjectType (e.g. "document") and
+// ObjectID (e.g. "42"). It exists alongside the coarse resource:action
+// permission system to express grants like "key X can manage document 42"
+// that Role.Permissions cannot.
+type ObjectACL struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	ObjectType string         `json:"object_type" gorm:"not null;uniqueIndex:idx_acl_object"`
+	ObjectID   string         `json:"object_id" gorm:"not null;uniqueIndex:idx_acl_object"`
+	Entries    []ACLEntry     `json:"entries" gorm:"foreignKey:ObjectACLID"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ACLEntry binds a single API key or role to a PermissionLevel on the
+// ObjectACL it belongs to. Exactly one of APIKeyID or RoleID should be set.
+type ACLEntry struct {
+	ID              uint            `json:"id" gorm:"primaryKey"`
+	ObjectACLID     uint            `json:"object_acl_id" gorm:"not null;index"`
+	APIKeyID        *uint           `json:"api_key_id,omitempty"`
+	RoleID          *uint           `json:"role_id,omitempty"`
+	PermissionLevel PermissionLevel `json:"permission_level" gorm:"not null"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// Grants reports whether this entry applies to the given API key (directly,
+// or via its role) and satisfies the required PermissionLevel.
+func (e *ACLEntry) Grants(apiKeyID, roleID uint, required PermissionLevel) bool {
+	if !e.PermissionLevel.Satisfies(required) {
+		return false
+	}
+	if e.APIKeyID != nil && *e.APIKeyID == apiKeyID {
+		return true
+	}
+	if e.RoleID != nil && *e.RoleID == roleID {
+		return true
+	}
+	return false
+}
+
+// CheckObjectPermission reports whether the given API key (and its role) is
+// granted at least the required PermissionLevel by any entry in the ACL.
+func (a *ObjectACL) CheckObjectPermission(apiKeyID, roleID uint, required PermissionLevel) bool {
+	for _, entry := range a.Entries {
+		if entry.Grants(apiKeyID, roleID, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessControlChangeList describes a diff-style update to an ObjectACL,
+// letting callers add and remove entries atomically in a single request
+// instead of replacing the whole entry list.
+type AccessControlChangeList struct {
+	Add    []ACLEntry `json:"add"`
+	Remove []ACLEntry `json:"remove"`
+}
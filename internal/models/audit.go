@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// AuditLog records a single security-relevant event: an authentication
+// attempt handled by APIKeyAuth/RootAPIKeyAuth, or a mutation performed by
+// an admin handler such as CreateRole/UpdateRole/DeleteRole. Entries are
+// written by internal/audit and are intentionally append-only; nothing in
+// the system updates or deletes an AuditLog row once created.
+type AuditLog struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Timestamp is when the event occurred, not when the row was written.
+	Timestamp time.Time `json:"timestamp" gorm:"index"`
+
+	// ActorKeyID is the API key that performed the action, if the request
+	// carried one. Nil for root-key-authenticated requests and for failed
+	// attempts that never resolved to a known key.
+	ActorKeyID *uint  `json:"actor_key_id,omitempty" gorm:"index"`
+	ActorIP    string `json:"actor_ip"`
+
+	// Action and Resource describe what happened, e.g. Action "role:create",
+	// Resource "role". ResourceID is the affected row's ID, when known.
+	Action     string `json:"action" gorm:"index"`
+	Resource   string `json:"resource" gorm:"index"`
+	ResourceID string `json:"resource_id,omitempty"`
+
+	// Result is "success" or "failure".
+	Result string `json:"result"`
+
+	// Details is a JSON-encoded object with event-specific context (e.g.
+	// the permissions granted to a newly created role). Empty when there's
+	// nothing beyond the fields above worth recording.
+	Details string `json:"details,omitempty" gorm:"type:text"`
+}
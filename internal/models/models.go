@@ -4,6 +4,7 @@
 package models
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -28,8 +29,23 @@ type Role struct {
 // Each API key is associated with a role that determines its permissions.
 // API keys can have an optional expiration date and track when they were last used.
 type APIKey struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Key         string         `json:"key" gorm:"unique;not null;index"`
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Key is the plaintext API key. It is never persisted (see KeyHash) and
+	// is only populated in memory, right after generation, so it can be
+	// returned to the caller exactly once.
+	Key string `json:"key,omitempty" gorm:"-"`
+
+	// KeyHash is the pepper-salted hash of Key, as produced by
+	// utils.HashAPIKey, and is what's actually looked up and compared
+	// in constant time during authentication.
+	KeyHash string `json:"-" gorm:"uniqueIndex;not null"`
+
+	// KeyPrefix is a short, non-secret slice of Key used to narrow a
+	// KeyHash lookup to a handful of candidate rows instead of scanning
+	// the whole table.
+	KeyPrefix string `json:"key_prefix" gorm:"index"`
+
 	RoleID      uint           `json:"role_id" gorm:"not null"`
 	Role        Role           `json:"role" gorm:"constraint:OnDelete:CASCADE;"`
 	Description string         `json:"description"`
@@ -37,6 +53,110 @@ type APIKey struct {
 	LastUsedAt  *time.Time     `json:"last_used_at"`
 	ExpiresAt   *time.Time     `json:"expires_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// CustomData is an opaque, caller-supplied JSON object (e.g. a user ID
+	// or username) stashed alongside the key at creation time. See
+	// GetCustomData.
+	CustomData string `json:"custom_data,omitempty" gorm:"type:text"`
+
+	// Owner identifies who the key was issued to (e.g. a username or team
+	// name). It's opaque to OrbitKeys beyond that: GetMyAPIKeys/DeleteMyAPIKey
+	// use it to scope a caller's non-root key to its own keys, without
+	// requiring a users table.
+	Owner string `json:"owner,omitempty" gorm:"index"`
+
+	// ReadOnly caps this key at ClassRead regardless of the permissions
+	// granted by its role, unless MaxClass overrides it with a higher cap.
+	ReadOnly bool `json:"read_only"`
+
+	// MaxClass, when set, caps the ActionClass this key may perform,
+	// taking precedence over ReadOnly. Nil means no per-key cap.
+	MaxClass *ActionClass `json:"max_class,omitempty"`
+
+	// Scopes, when set, narrows this key to a subset of its Role's
+	// permissions: the key's effective permission set is the intersection
+	// of Role.GetPermissions() and GetScopes(), checked via ScopesAllow.
+	// Stored as a comma-separated string with the same grammar as
+	// Role.Permissions. An empty Scopes leaves the key unrestricted by this
+	// mechanism, granted everything its role allows, as before.
+	Scopes string `json:"scopes,omitempty" gorm:"type:text"`
+
+	// PreviousKeyHash, PreviousKeyPrefix, and PreviousKeyExpiresAt support
+	// zero-downtime rotation: RotateAPIKey moves the current KeyHash/KeyPrefix
+	// here and sets PreviousKeyExpiresAt to the requested grace period, so
+	// callers still holding the old plaintext can keep authenticating with it
+	// until the grace period elapses, while new calls should use the newly
+	// issued key.
+	PreviousKeyHash      string     `json:"-" gorm:"index"`
+	PreviousKeyPrefix    string     `json:"-" gorm:"index"`
+	PreviousKeyExpiresAt *time.Time `json:"-"`
+
+	// Format records which shape Key was minted in: FormatOpaque (default)
+	// for a random token looked up via KeyHash, or FormatJWT for a
+	// self-contained, statelessly-verifiable token. See utils.CreateAPIKey
+	// and utils.FindAPIKeyByPlaintext.
+	Format string `json:"format,omitempty"`
+}
+
+// AppRole represents a Vault-style AppRole: a stable, non-secret identifier
+// (RoleID) bound to a set of permissions, used to issue short-lived tokens
+// to unattended workloads. Callers authenticate by pairing the RoleID with
+// a rotating SecretID rather than holding a single long-lived credential.
+type AppRole struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	RoleID           string         `json:"role_id" gorm:"unique;not null;index"`
+	Name             string         `json:"name" gorm:"unique;not null"`
+	Description      string         `json:"description"`
+	BoundPermissions string         `json:"bound_permissions" gorm:"type:text"` // Stored as comma-separated string, same grammar as Role.Permissions
+	SecretIDTTL      time.Duration  `json:"secret_id_ttl"`                      // 0 means SecretIDs never expire
+	TokenTTL         time.Duration  `json:"token_ttl"`                          // TTL applied to tokens minted from a login
+	SecretIDNumUses  int            `json:"secret_id_num_uses"`                 // 0 means unlimited uses
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+	SecretIDs        []SecretID     `json:"-" gorm:"foreignKey:AppRoleID"`
+}
+
+// GetBoundPermissions returns the AppRole's bound permissions as a slice,
+// using the same comma-separated encoding as Role.Permissions.
+func (a *AppRole) GetBoundPermissions() []string {
+	return ParsePermissions(a.BoundPermissions)
+}
+
+// SecretID represents a rotating credential bound to an AppRole. Only the
+// SHA-256 hash of the secret is persisted; the plaintext value is returned
+// to the caller exactly once, at generation time.
+type SecretID struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	AppRoleID        uint           `json:"app_role_id" gorm:"not null;index"`
+	AppRole          AppRole        `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	SecretIDHash     string         `json:"-" gorm:"not null;index"`
+	NumUsesRemaining int            `json:"num_uses_remaining"` // -1 means unlimited
+	ExpiresAt        *time.Time     `json:"expires_at"`
+	CIDRBound        string         `json:"cidr_bound"` // Optional CIDR the SecretID may be used from, empty means unrestricted
+	CreatedAt        time.Time      `json:"created_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// IsExpired reports whether the SecretID is past its expiration or has no uses remaining.
+func (s *SecretID) IsExpired() bool {
+	if s.NumUsesRemaining == 0 {
+		return true
+	}
+	if s.ExpiresAt == nil {
+		return false
+	}
+	return s.ExpiresAt.Before(time.Now())
+}
+
+// IssuedToken records a short-lived token minted by an AppRole login so that
+// the exchange can be audited and revoked independently of the APIKey it backs.
+type IssuedToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	AppRoleID uint       `json:"app_role_id" gorm:"not null;index"`
+	APIKeyID  uint       `json:"api_key_id" gorm:"not null;index"`
+	IssuedAt  time.Time  `json:"issued_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 // GetPermissions returns a slice of permissions for the role by parsing
@@ -61,12 +181,12 @@ func (r *Role) AddPermission(permission string) {
 	if !ValidatePermissionFormat(permission) {
 		return
 	}
-	
+
 	currentPerms := r.GetPermissions()
 	if contains(currentPerms, permission) {
 		return // Already has this permission
 	}
-	
+
 	if r.Permissions == "" {
 		r.Permissions = permission
 	} else {
@@ -79,13 +199,13 @@ func (r *Role) AddPermission(permission string) {
 func (r *Role) RemovePermission(permission string) {
 	currentPerms := r.GetPermissions()
 	newPerms := make([]string, 0)
-	
+
 	for _, p := range currentPerms {
 		if p != permission {
 			newPerms = append(newPerms, p)
 		}
 	}
-	
+
 	r.Permissions = strings.Join(newPerms, ",")
 }
 
@@ -99,11 +219,58 @@ func (k *APIKey) IsExpired() bool {
 	return k.ExpiresAt.Before(time.Now())
 }
 
-// UpdateLastUsed updates the LastUsedAt field of the API key to the current time.
-// This is called whenever an API key is used for authentication to track usage.
+// GetCustomData parses CustomData as a JSON object and returns it. Returns
+// nil with no error if CustomData is empty, since an API key created
+// without custom data is the common case, not a malformed one.
+func (k *APIKey) GetCustomData() (map[string]interface{}, error) {
+	if k.CustomData == "" {
+		return nil, nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(k.CustomData), &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// lastUsedThrottle is the minimum interval between persisted LastUsedAt
+// updates for a single key, so a hot key's every request doesn't turn into a
+// write on the APIKey row.
+const lastUsedThrottle = 1 * time.Minute
+
+// UpdateLastUsed updates the LastUsedAt field of the API key to the current
+// time. This is called whenever an API key is used for authentication to
+// track usage, but is throttled to at most once per lastUsedThrottle per key:
+// when called again sooner it returns nil without writing.
 // Returns an error if the database update fails.
 func (k *APIKey) UpdateLastUsed(db *gorm.DB) error {
 	now := time.Now()
+	if k.LastUsedAt != nil && now.Sub(*k.LastUsedAt) < lastUsedThrottle {
+		return nil
+	}
 	k.LastUsedAt = &now
 	return db.Model(k).Update("last_used_at", now).Error
-} 
\ No newline at end of file
+}
+
+// GetScopes returns the API key's scopes as a slice, using the same
+// comma-separated encoding as Role.Permissions. Returns an empty slice if
+// no scopes are assigned.
+func (k *APIKey) GetScopes() []string {
+	return ParsePermissions(k.Scopes)
+}
+
+// ScopesAllow reports whether permission is allowed by the key's Scopes. A
+// key with no scopes configured is unrestricted by this check, so it's
+// granted whatever its role allows, same as before Scopes existed. A key
+// with scopes set is only ever narrowed by them, never widened beyond what
+// its role grants: callers must still check the role's permissions
+// separately and require both to allow the request.
+func (k *APIKey) ScopesAllow(permission string) bool {
+	scopes := k.GetScopes()
+	if len(scopes) == 0 {
+		return true
+	}
+	return CheckPermission(permission, scopes)
+}
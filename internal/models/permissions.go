@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -77,6 +78,88 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// ActionClass categorizes a permission's action by whether it can mutate
+// data, so that read-only keys can be capped even if their role's
+// resource:action permissions would otherwise allow a write.
+type ActionClass int
+
+const (
+	// ClassRead covers non-mutating actions (read, list, get).
+	ClassRead ActionClass = iota
+
+	// ClassWrite covers mutating actions (create, update, delete).
+	ClassWrite
+
+	// ClassAdmin covers wildcard and any action this system doesn't
+	// recognize; unrecognized actions are treated conservatively as the
+	// highest class so an unclassified action can't slip past a cap.
+	ClassAdmin
+)
+
+// String returns a human-readable name for the ActionClass, used in error
+// messages when a key's cap rejects a request.
+func (c ActionClass) String() string {
+	switch c {
+	case ClassRead:
+		return "read"
+	case ClassWrite:
+		return "write"
+	default:
+		return "admin"
+	}
+}
+
+// ClassifyAction infers the ActionClass of a "resource:action" permission
+// string from its action suffix. The wildcard action (*) inherits the
+// highest class, since it can resolve to any action at check time.
+func ClassifyAction(permission string) ActionClass {
+	action := permission
+	if parts := strings.Split(permission, PermissionSeparator); len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch action {
+	case "read", "list", "get":
+		return ClassRead
+	case "create", "update", "delete":
+		return ClassWrite
+	default:
+		return ClassAdmin
+	}
+}
+
+// CheckPermissionForAction extends CheckPermission with the mutating/
+// read-only cap carried by an APIKey: a key is only authorized if its role
+// grants the required permission AND the required action's class doesn't
+// exceed the key's cap (APIKey.MaxClass if set, else ClassRead when
+// APIKey.ReadOnly, else no cap).
+//
+// Returns false with an error describing the rejected class when the
+// permission matches but the key's cap forbids it, so that callers (e.g.
+// middleware) can distinguish "no permission" from "capped by ReadOnly".
+func CheckPermissionForAction(required string, userPermissions []string, key *APIKey) (bool, error) {
+	if !CheckPermission(required, userPermissions) {
+		return false, nil
+	}
+
+	if key == nil {
+		return true, nil
+	}
+
+	maxClass := ClassAdmin
+	if key.MaxClass != nil {
+		maxClass = *key.MaxClass
+	} else if key.ReadOnly {
+		maxClass = ClassRead
+	}
+
+	if requiredClass := ClassifyAction(required); requiredClass > maxClass {
+		return false, fmt.Errorf("action class %q exceeds key's maximum allowed class %q", requiredClass, maxClass)
+	}
+
+	return true, nil
+}
+
 // ValidatePermissionFormat checks if a permission string follows the correct format.
 // Valid formats are:
 // - Wildcard (*) for all permissions
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestClassifyAction(t *testing.T) {
+	testCases := []struct {
+		permission string
+		expected   ActionClass
+	}{
+		{"users:read", ClassRead},
+		{"users:list", ClassRead},
+		{"users:get", ClassRead},
+		{"products:create", ClassWrite},
+		{"products:update", ClassWrite},
+		{"products:delete", ClassWrite},
+		{"admin:*", ClassAdmin},
+		{"users:unknown-action", ClassAdmin},
+		{"*", ClassAdmin},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.permission, func(t *testing.T) {
+			if got := ClassifyAction(tc.permission); got != tc.expected {
+				t.Errorf("ClassifyAction(%q) = %v, want %v", tc.permission, got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestCheckPermissionForAction groups cases by whether the action mutates
+// data, asserting that every permission behaves correctly against both a
+// full key and a read-only key.
+func TestCheckPermissionForAction(t *testing.T) {
+	userPermissions := []string{"products:*"}
+
+	readOnlyKey := &APIKey{ReadOnly: true}
+	fullKey := &APIKey{ReadOnly: false}
+
+	mutatingActions := []string{"products:create", "products:update", "products:delete"}
+	nonMutatingActions := []string{"products:read", "products:list", "products:get"}
+
+	t.Run("read-only key allows non-mutating actions", func(t *testing.T) {
+		for _, action := range nonMutatingActions {
+			allowed, err := CheckPermissionForAction(action, userPermissions, readOnlyKey)
+			if err != nil {
+				t.Errorf("CheckPermissionForAction(%q) returned unexpected error: %v", action, err)
+			}
+			if !allowed {
+				t.Errorf("Expected read-only key to be allowed %q", action)
+			}
+		}
+	})
+
+	t.Run("read-only key rejects mutating actions", func(t *testing.T) {
+		for _, action := range mutatingActions {
+			allowed, err := CheckPermissionForAction(action, userPermissions, readOnlyKey)
+			if allowed {
+				t.Errorf("Expected read-only key to be rejected for %q", action)
+			}
+			if err == nil {
+				t.Errorf("Expected an error explaining why %q was rejected", action)
+			}
+		}
+	})
+
+	t.Run("full key allows mutating and non-mutating actions", func(t *testing.T) {
+		for _, action := range append(mutatingActions, nonMutatingActions...) {
+			allowed, err := CheckPermissionForAction(action, userPermissions, fullKey)
+			if err != nil {
+				t.Errorf("CheckPermissionForAction(%q) returned unexpected error: %v", action, err)
+			}
+			if !allowed {
+				t.Errorf("Expected full key to be allowed %q", action)
+			}
+		}
+	})
+
+	t.Run("MaxClass overrides ReadOnly", func(t *testing.T) {
+		writeClass := ClassWrite
+		keyWithCap := &APIKey{ReadOnly: true, MaxClass: &writeClass}
+
+		allowed, err := CheckPermissionForAction("products:update", userPermissions, keyWithCap)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !allowed {
+			t.Error("Expected MaxClass of ClassWrite to permit a write action despite ReadOnly being true")
+		}
+
+		allowed, err = CheckPermissionForAction("products:delete_all", userPermissions, keyWithCap)
+		_ = err
+		if allowed {
+			t.Error("Expected an unrecognized (ClassAdmin) action to still be rejected by a ClassWrite cap")
+		}
+	})
+
+	t.Run("permission not granted short-circuits before class check", func(t *testing.T) {
+		allowed, err := CheckPermissionForAction("orders:read", []string{"products:*"}, readOnlyKey)
+		if allowed {
+			t.Error("Expected no permission for an ungranted resource")
+		}
+		if err != nil {
+			t.Errorf("Expected no error when the permission simply isn't granted, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+// Package policy wraps a Casbin enforcer backed by the OrbitKeys SQLite
+// database, so that role-to-permission mappings can be edited at runtime
+// instead of being fixed at process start.
+package policy
+
+import (
+	"fmt"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"gorm.io/gorm"
+)
+
+// modelText defines an RBAC-with-resources model: subjects are role names,
+// objects are the resource half of a "resource:action" permission (or an
+// object pattern such as "projects/*/keys"), and the request matcher
+// supports both resource and role-inheritance wildcards via keyMatch and
+// Casbin's built-in role manager (g).
+const modelText = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && keyMatch2(r.obj, p.obj) && (keyMatch(r.act, p.act) || p.act == "*")
+`
+
+// NewEnforcer builds a Casbin enforcer whose policy is persisted in db via
+// the Gorm adapter, so that policy changes made through the admin endpoints
+// survive restarts and are immediately visible to every enforcement call.
+func NewEnforcer(db *gorm.DB) (*casbin.Enforcer, error) {
+	adapter, err := gormadapter.NewAdapterByDB(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy adapter: %w", err)
+	}
+
+	m, err := model.NewModelFromString(modelText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy model: %w", err)
+	}
+
+	enforcer, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create enforcer: %w", err)
+	}
+
+	if err := enforcer.LoadPolicy(); err != nil {
+		return nil, fmt.Errorf("failed to load policy: %w", err)
+	}
+
+	return enforcer, nil
+}
+
+// MigrateRolePermissions translates every Role's comma-separated
+// Permissions string into Casbin p-lines ("resource", "action") scoped to
+// that role's own name as the subject, and adds a g-line so the role
+// inherits its own policies as a subject. It is idempotent: AddPolicy/
+// AddGroupingPolicy are no-ops when the line already exists.
+//
+// This is run once at startup so that roles created before the policy
+// engine was introduced keep working without manual intervention.
+func MigrateRolePermissions(enforcer *casbin.Enforcer, roles []models.Role) error {
+	for _, role := range roles {
+		if _, err := enforcer.AddGroupingPolicy(role.Name, role.Name); err != nil {
+			return fmt.Errorf("failed to add self-grouping policy for role %q: %w", role.Name, err)
+		}
+
+		for _, permission := range role.GetPermissions() {
+			resource, action := splitPermission(permission)
+			if _, err := enforcer.AddPolicy(role.Name, resource, action); err != nil {
+				return fmt.Errorf("failed to migrate permission %q for role %q: %w", permission, role.Name, err)
+			}
+		}
+	}
+
+	return enforcer.SavePolicy()
+}
+
+// Enforce checks whether role is authorized to perform action on resource,
+// where resource and action come from the existing "resource:action"
+// permission grammar (see models.FormatPermission).
+func Enforce(enforcer *casbin.Enforcer, roleName, resource, action string) (bool, error) {
+	return enforcer.Enforce(roleName, resource, action)
+}
+
+// CheckPermission decodes a "resource:action" permission string (the same
+// grammar models.CheckPermission accepts) and enforces it for roleName.
+// It is the entry point middleware.APIKeyAuth/RequirePermission use once
+// the policy engine is installed.
+func CheckPermission(enforcer *casbin.Enforcer, roleName, permission string) (bool, error) {
+	resource, action := splitPermission(permission)
+	return enforcer.Enforce(roleName, resource, action)
+}
+
+// activeEnforcer is the process-wide enforcer installed at startup by
+// SetEnforcer. It is nil until the policy engine has been initialized,
+// which callers must treat as "policy engine disabled".
+var activeEnforcer *casbin.Enforcer
+
+// SetEnforcer installs the process-wide enforcer used by the policy admin
+// endpoints and by APIKeyAuth/RequirePermission for permission checks.
+func SetEnforcer(e *casbin.Enforcer) {
+	activeEnforcer = e
+}
+
+// GetEnforcer returns the process-wide enforcer, or nil if the policy
+// engine hasn't been initialized.
+func GetEnforcer() *casbin.Enforcer {
+	return activeEnforcer
+}
+
+// splitPermission decodes a "resource:action" string (or the bare wildcard)
+// into the resource/action pair Casbin policies are keyed on.
+func splitPermission(permission string) (resource, action string) {
+	if permission == models.WildcardPermission {
+		return "*", "*"
+	}
+
+	parts := []rune(permission)
+	sep := -1
+	for i, r := range parts {
+		if string(r) == models.PermissionSeparator {
+			sep = i
+			break
+		}
+	}
+	if sep == -1 {
+		return permission, "*"
+	}
+
+	return permission[:sep], permission[sep+1:]
+}
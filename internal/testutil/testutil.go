@@ -0,0 +1,131 @@
+// Package testutil provides a shared in-memory SQLite harness for tests
+// across OrbitKeys packages, and for downstream projects that embed
+// OrbitKeys and want to exercise its middleware without standing up a real
+// database.
+package testutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BasementPilot/orbit-keys/internal/database"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SetupTestDB opens a fresh in-memory SQLite database, runs the same
+// AutoMigrate production uses, and installs it as the package-level handle
+// returned by database.GetDB() (and therefore used by APIKeyAuth and
+// RootAPIKeyAuth). name should be unique per test so that parallel tests
+// don't share state through SQLite's cache=shared mode.
+func SetupTestDB(name string) (*gorm.DB, error) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", name)
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open in-memory database: %w", err)
+	}
+
+	if err := db.AutoMigrate(
+		&models.Role{}, &models.APIKey{},
+		&models.AppRole{}, &models.SecretID{}, &models.IssuedToken{},
+		&models.ObjectACL{}, &models.ACLEntry{}, &models.AuditLog{},
+	); err != nil {
+		return nil, fmt.Errorf("failed to migrate in-memory database: %w", err)
+	}
+
+	database.DB = db
+	return db, nil
+}
+
+// Fixtures bundles the seed data created by SeedFixtures, so tests can
+// reference known roles/keys without re-querying the database.
+type Fixtures struct {
+	AdminRole     models.Role
+	ReadOnlyRole  models.Role
+	UnrelatedRole models.Role
+	AdminKey      models.APIKey // wildcard (*) permission via AdminRole
+	ReadOnlyKey   models.APIKey // products:read permission via ReadOnlyRole
+	UnrelatedKey  models.APIKey // orders:read permission via UnrelatedRole, distinct from products:read
+	ExpiredKey    models.APIKey // valid format, but ExpiresAt is in the past
+}
+
+// SeedFixtures creates a small set of roles and API keys covering the cases
+// most middleware tests need: a wildcard admin key, a narrowly-scoped
+// read-only key, and an already-expired key.
+func SeedFixtures(db *gorm.DB) (*Fixtures, error) {
+	adminRole := models.Role{Name: "test-admin", Permissions: "*"}
+	if err := db.Create(&adminRole).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed admin role: %w", err)
+	}
+
+	readOnlyRole := models.Role{Name: "test-readonly", Permissions: "products:read"}
+	if err := db.Create(&readOnlyRole).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed read-only role: %w", err)
+	}
+
+	adminKey, err := utils.CreateAPIKey(adminRole.ID, "test admin key", "", "", utils.FormatOpaque, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate admin key: %w", err)
+	}
+	if err := db.Create(adminKey).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed admin key: %w", err)
+	}
+
+	readOnlyKey, err := utils.CreateAPIKey(readOnlyRole.ID, "test read-only key", "", "", utils.FormatOpaque, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate read-only key: %w", err)
+	}
+	if err := db.Create(readOnlyKey).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed read-only key: %w", err)
+	}
+
+	unrelatedRole := models.Role{Name: "test-unrelated", Permissions: "orders:read"}
+	if err := db.Create(&unrelatedRole).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed unrelated role: %w", err)
+	}
+
+	unrelatedKey, err := utils.CreateAPIKey(unrelatedRole.ID, "test unrelated-permission key", "", "", utils.FormatOpaque, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate unrelated-permission key: %w", err)
+	}
+	if err := db.Create(unrelatedKey).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed unrelated-permission key: %w", err)
+	}
+
+	expiredKeyValue, err := utils.GenerateAPIKey(utils.DefaultKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate expired key: %w", err)
+	}
+	expiredKeyHash, err := utils.HashAPIKey(expiredKeyValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash expired key: %w", err)
+	}
+	pastExpiry := time.Now().Add(-1 * time.Hour)
+	expiredKey := models.APIKey{
+		Key:         expiredKeyValue,
+		KeyHash:     expiredKeyHash,
+		KeyPrefix:   utils.KeyLookupPrefix(expiredKeyValue),
+		RoleID:      readOnlyRole.ID,
+		Description: "test expired key",
+		ExpiresAt:   &pastExpiry,
+	}
+	if err := db.Create(&expiredKey).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed expired key: %w", err)
+	}
+
+	return &Fixtures{
+		AdminRole:     adminRole,
+		ReadOnlyRole:  readOnlyRole,
+		UnrelatedRole: unrelatedRole,
+		AdminKey:      *adminKey,
+		ReadOnlyKey:   *readOnlyKey,
+		UnrelatedKey:  *unrelatedKey,
+		ExpiredKey:    expiredKey,
+	}, nil
+}
@@ -0,0 +1,198 @@
+// Package tokens issues and verifies RS256-signed JWT session tokens that
+// sit alongside OrbitKeys' opaque API keys, and tracks refresh-token
+// revocation in Redis so a logout takes effect across every instance
+// behind a load balancer.
+package tokens
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Claims are embedded in an access token's payload. They name the API key
+// and role that middleware.JWTAuth loads from the database to populate the
+// same request-context values APIKeyAuth does.
+type Claims struct {
+	APIKeyID uint   `json:"api_key_id"`
+	RoleName string `json:"role_name"`
+	jwt.RegisteredClaims
+}
+
+// ErrRevoked is returned by Store.CheckRefreshToken when the presented jti
+// has been logged out or never existed.
+var ErrRevoked = errors.New("refresh token has been revoked or does not exist")
+
+// NewAccessToken mints a short-lived RS256 access token for apiKeyID/
+// roleName, signed with privateKey and valid for ttl.
+func NewAccessToken(privateKey *rsa.PrivateKey, apiKeyID uint, roleName string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		APIKeyID: apiKeyID,
+		RoleName: roleName,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	signed, err := token.SignedString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return signed, nil
+}
+
+// ParseAccessToken verifies tokenString's RS256 signature against
+// publicKey and returns its claims. It does not consult Redis; callers
+// that need revocation (refresh tokens) should use Store.CheckRefreshToken
+// as well.
+func ParseAccessToken(publicKey *rsa.PublicKey, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Method)
+		}
+		return publicKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %w", err)
+	}
+
+	return claims, nil
+}
+
+// NewRefreshToken mints an RS256 refresh token carrying jti as its
+// RegisteredClaims.ID, signed with privateKey and valid for ttl.
+func NewRefreshToken(privateKey *rsa.PrivateKey, jti string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		ID:        jti,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	signed, err := token.SignedString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign refresh token: %w", err)
+	}
+
+	return signed, nil
+}
+
+// ParseRefreshToken verifies tokenString's RS256 signature against
+// publicKey and returns its jti (RegisteredClaims.ID).
+func ParseRefreshToken(publicKey *rsa.PublicKey, tokenString string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Method)
+		}
+		return publicKey, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid refresh token: %w", err)
+	}
+
+	return claims.ID, nil
+}
+
+// Store tracks refresh-token jtis in Redis so a logout or rotation is
+// visible to every OrbitKeys instance immediately.
+type Store struct {
+	client *redis.Client
+}
+
+// NewStore connects to the Redis instance at redisURL.
+func NewStore(redisURL string) (*Store, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	return &Store{client: redis.NewClient(opts)}, nil
+}
+
+// IssueRefreshToken generates a new refresh-token jti bound to apiKeyID,
+// records it in Redis with a TTL matching ttl, and returns the jti to
+// embed in the refresh token's claims.
+func (s *Store) IssueRefreshToken(ctx context.Context, apiKeyID uint, ttl time.Duration) (string, error) {
+	jti := uuid.NewString()
+	key := refreshKey(jti)
+
+	if err := s.client.Set(ctx, key, apiKeyID, ttl).Err(); err != nil {
+		return "", fmt.Errorf("failed to record refresh token: %w", err)
+	}
+
+	return jti, nil
+}
+
+// CheckRefreshToken verifies that jti is still valid (not logged out or
+// expired) and returns the APIKeyID it was issued for.
+func (s *Store) CheckRefreshToken(ctx context.Context, jti string) (uint, error) {
+	apiKeyID, err := s.client.Get(ctx, refreshKey(jti)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrRevoked
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+
+	return uint(apiKeyID), nil
+}
+
+// RevokeRefreshToken deletes jti from Redis, logging the session out
+// immediately.
+func (s *Store) RevokeRefreshToken(ctx context.Context, jti string) error {
+	if err := s.client.Del(ctx, refreshKey(jti)).Err(); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// refreshKey namespaces refresh-token jtis in the shared Redis keyspace.
+func refreshKey(jti string) string {
+	return "orbitkeys:refresh:" + jti
+}
+
+// LoadPrivateKey reads and parses a PEM-encoded PKCS#1 or PKCS#8 RSA
+// private key from path, as pointed at by ORBITKEYS_JWT_PRIVATE_KEY_PATH.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key: %w", err)
+	}
+
+	return key, nil
+}
+
+// LoadPublicKey reads and parses a PEM-encoded RSA public key from path, as
+// pointed at by ORBITKEYS_JWT_PUBLIC_KEY_PATH.
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+	}
+
+	return key, nil
+}
@@ -4,7 +4,9 @@
 package orbitkeys
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/rsa"
 	"encoding/base64"
 	"log"
 
@@ -13,9 +15,14 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/BasementPilot/orbit-keys/config"
+	"github.com/BasementPilot/orbit-keys/internal/audit"
+	"github.com/BasementPilot/orbit-keys/internal/connectors"
 	"github.com/BasementPilot/orbit-keys/internal/database"
 	"github.com/BasementPilot/orbit-keys/internal/handlers"
 	"github.com/BasementPilot/orbit-keys/internal/middleware"
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/BasementPilot/orbit-keys/internal/policy"
+	"github.com/BasementPilot/orbit-keys/internal/tokens"
 )
 
 // OrbitKeys represents the API key management system with its configuration and web server.
@@ -24,6 +31,18 @@ import (
 type OrbitKeys struct {
 	Config *config.Config
 	App    *fiber.App
+
+	// jwtPublicKey verifies session JWTs minted by handlers.IssueToken. It
+	// is nil unless Config.JWTEnabled() was true during New().
+	jwtPublicKey *rsa.PublicKey
+
+	// auditCloser releases resources (e.g. an open audit log file) held by
+	// the sinks installed during New(). Nil if nothing needs closing.
+	auditCloser func() error
+
+	// watchCancel stops the background goroutine started by
+	// StartConfigWatch, if one is running. Nil otherwise.
+	watchCancel context.CancelFunc
 }
 
 // New creates and initializes a new OrbitKeys instance.
@@ -34,7 +53,10 @@ type OrbitKeys struct {
 // Returns the initialized OrbitKeys instance and any error encountered during setup.
 func New() (*OrbitKeys, error) {
 	// Load configuration
-	cfg := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	// Generate root API key if none is provided
 	if cfg.RootAPIKey == "" {
@@ -52,16 +74,116 @@ func New() (*OrbitKeys, error) {
 		}
 	}
 
+	// Generate a per-install pepper for hashing API keys at rest if none is
+	// configured, so hashes aren't portable to a database dump taken from a
+	// different deployment.
+	if cfg.KeyPepper == "" {
+		pepper, err := generateRootAPIKey()
+		if err != nil {
+			return nil, err
+		}
+		cfg.KeyPepper = pepper
+
+		if err := config.SaveConfig(cfg); err != nil {
+			log.Printf("Warning: Failed to save key pepper to .env file: %v", err)
+		} else {
+			log.Println("Generated a new API key hashing pepper and saved it to the .env file")
+		}
+	}
+
+	// Make cfg the configuration RootAPIKeyAuth reads through, so that once
+	// StartConfigWatch is running, a reload takes effect on the next request
+	// without needing every existing *config.Config closure replaced.
+	config.SetCurrent(cfg)
+
 	// Initialize database
 	if err := database.InitDB(cfg); err != nil {
 		return nil, err
 	}
 
+	// Configure AppRole default TTLs for roles created without explicit values
+	handlers.SetAppRoleDefaults(cfg.AppRoleDefaultSecretIDTTL, cfg.AppRoleDefaultTokenTTL)
+
+	// Install the brute-force attempt tracker and rate limiter storage
+	// appropriate for cfg: Redis-backed and shared across instances when
+	// cfg.RedisURL is set, otherwise process-local fallbacks.
+	if err := middleware.ConfigureBruteForceTracking(cfg); err != nil {
+		return nil, err
+	}
+
+	// Load JWT session token signing keys and Redis-backed revocation
+	// store, if configured
+	var jwtPublicKey *rsa.PublicKey
+	if cfg.JWTEnabled() {
+		privateKey, err := tokens.LoadPrivateKey(cfg.JWTPrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey, err := tokens.LoadPublicKey(cfg.JWTPublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		jwtPublicKey = publicKey
+
+		refreshStore, err := tokens.NewStore(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+
+		handlers.SetJWTConfig(privateKey, publicKey, refreshStore, cfg.JWTAccessTokenTTL, cfg.JWTRefreshTokenTTL)
+	}
+
+	// Load identity-provider connectors, if configured
+	if cfg.ConnectorsFile != "" {
+		fileConfig, err := connectors.LoadFileConfig(cfg.ConnectorsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		registry, err := connectors.BuildRegistry(fileConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		handlers.SetConnectorRegistry(registry)
+	}
+
 	// Create default admin role
 	if err := database.CreateDefaultAdminRole(); err != nil {
 		return nil, err
 	}
 
+	// Initialize the Casbin policy engine against the same database, and
+	// migrate any existing Role.Permissions strings into policy lines so
+	// roles created before the policy engine existed keep working.
+	enforcer, err := policy.NewEnforcer(database.GetDB())
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []models.Role
+	if err := database.GetDB().Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	if err := policy.MigrateRolePermissions(enforcer, roles); err != nil {
+		return nil, err
+	}
+
+	policy.SetEnforcer(enforcer)
+
+	// Wire up the audit log: SQLite is always written to, plus a JSONL
+	// file and/or webhook when configured, so external SIEM systems can
+	// ingest events without giving up the local trail.
+	sinks, auditCloser, err := audit.BuildSinks(audit.Config{
+		File:       cfg.AuditFile,
+		WebhookURL: cfg.AuditWebhookURL,
+	}, database.GetDB())
+	if err != nil {
+		return nil, err
+	}
+	audit.SetSinks(sinks)
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -80,11 +202,15 @@ func New() (*OrbitKeys, error) {
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New())
+	app.Use(middleware.CreateRateLimiter(cfg.RateLimitMax, cfg.RateLimitWindow))
+	app.Use(middleware.AuditMiddleware())
 
 	// Create OrbitKeys instance
 	orbitKeys := &OrbitKeys{
-		Config: cfg,
-		App:    app,
+		Config:       cfg,
+		App:          app,
+		jwtPublicKey: jwtPublicKey,
+		auditCloser:  auditCloser,
 	}
 
 	// Setup routes
@@ -115,6 +241,9 @@ func (o *OrbitKeys) setupRoutes() {
 	admin.Get("/api-keys/:id", handlers.GetAPIKey)
 	admin.Delete("/api-keys/:id", handlers.DeleteAPIKey)
 	admin.Patch("/api-keys/:id/expiration", handlers.UpdateAPIKeyExpiration)
+	admin.Post("/api-keys/revoke", handlers.RevokeAPIKeys)
+	admin.Post("/api-keys/:id/rotate", handlers.RotateAPIKey)
+	admin.Post("/api-keys/:id/revoke", handlers.RevokeAPIKey)
 
 	// Role Management
 	admin.Post("/roles", handlers.CreateRole)
@@ -127,6 +256,53 @@ func (o *OrbitKeys) setupRoutes() {
 	admin.Get("/lookup-key", handlers.LookupAPIKey)
 	admin.Get("/validate-permission", handlers.ValidateAPIKeyPermission)
 
+	// Object-level ACLs - protected by root API key
+	admin.Get("/acl/:objectType/:objectId", handlers.GetObjectACL)
+	admin.Put("/acl/:objectType/:objectId", handlers.PutObjectACL)
+
+	// Policy engine management - protected by root API key
+	admin.Get("/policies", handlers.GetPolicies)
+	admin.Post("/policies", handlers.CreatePolicy)
+	admin.Delete("/policies", handlers.DeletePolicy)
+
+	// AppRole Management - protected by root API key
+	if o.Config.AppRoleEnabled {
+		admin.Post("/approles", handlers.CreateAppRole)
+		admin.Get("/approles", handlers.GetAppRoles)
+		admin.Get("/approles/:id", handlers.GetAppRole)
+		admin.Delete("/approles/:id", handlers.DeleteAppRole)
+		admin.Post("/approles/:id/secret-id", handlers.GenerateAppRoleSecretID)
+
+		// AppRole login is unauthenticated: the role_id/secret_id pair is the credential
+		api.Post("/auth/approle/login", handlers.AppRoleLogin)
+	}
+
+	// JWT session tokens - issuance/refresh/logout are unauthenticated
+	// beyond the credential they themselves consume (an API key or a
+	// refresh token)
+	if o.Config.JWTEnabled() {
+		api.Post("/auth/token", handlers.IssueToken)
+		api.Post("/auth/token/refresh", handlers.RefreshToken)
+		api.Post("/auth/logout", handlers.Logout)
+	}
+
+	// Identity-provider connector login/callback - unauthenticated, the handshake itself is the credential
+	if o.Config.ConnectorsFile != "" {
+		api.Get("/auth/:connector/login", handlers.ConnectorLogin)
+		api.Post("/auth/:connector/login", handlers.ConnectorLogin)
+		api.Get("/auth/:connector/callback", handlers.ConnectorCallback)
+	}
+
+	// Audit log - protected by root API key
+	api.Get("/audit", middleware.RootAPIKeyAuth(o.Config), handlers.GetAuditLogs)
+
+	// Self-service key management - protected by any valid (non-root) API
+	// key, scoped to keys sharing the caller's own Owner
+	my := api.Group("/my")
+	my.Use(middleware.APIKeyAuth(""))
+	my.Get("/api-keys", handlers.GetMyAPIKeys)
+	my.Delete("/api-keys/:id", handlers.DeleteMyAPIKey)
+
 	// Public API health check
 	api.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -146,6 +322,17 @@ func (o *OrbitKeys) GetMiddleware(permission string) fiber.Handler {
 	return middleware.APIKeyAuth(permission)
 }
 
+// GetJWTMiddleware returns middleware for validating the JWT session tokens
+// issued by POST {BaseURL}/auth/token, as an alternative to GetMiddleware's
+// API-key-header flow. It panics if called before Config.JWTEnabled() was
+// true during New(), since there is no signing key to verify against.
+func (o *OrbitKeys) GetJWTMiddleware() fiber.Handler {
+	if o.jwtPublicKey == nil {
+		panic("orbitkeys: GetJWTMiddleware called but JWT session tokens are not enabled")
+	}
+	return middleware.JWTAuth(o.jwtPublicKey)
+}
+
 // RequirePermission returns middleware to check if the authenticated API key has a specific permission.
 // This middleware should be used after the API key authentication middleware (GetMiddleware)
 // to perform additional permission checks.
@@ -155,6 +342,42 @@ func (o *OrbitKeys) RequirePermission(permission string) fiber.Handler {
 	return middleware.RequirePermission(permission)
 }
 
+// StartConfigWatch watches the .env file and listens for SIGHUP, reloading
+// the configuration on either (see config.Watch). The rotated RootAPIKey
+// takes effect immediately, since RootAPIKeyAuth reads through
+// config.Current(). A changed DBPath reopens the GORM connection; a changed
+// BaseURL is logged but not applied, since Fiber route groups can't be
+// re-mounted under a new prefix without a restart.
+//
+// The watch goroutine runs until o.Close() is called.
+func (o *OrbitKeys) StartConfigWatch() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.watchCancel = cancel
+
+	go func() {
+		err := config.Watch(ctx, func(cfg *config.Config) {
+			if cfg.BaseURL != o.Config.BaseURL {
+				log.Printf("Warning: ORBITKEYS_BASE_URL changed to %q, but route mounts can only be changed by restarting", cfg.BaseURL)
+			}
+
+			if cfg.DBPath != o.Config.DBPath {
+				log.Printf("DB path changed to %q, reopening database connection", cfg.DBPath)
+				if err := database.InitDB(cfg); err != nil {
+					log.Printf("Warning: failed to reopen database connection after config reload: %v", err)
+					return
+				}
+			}
+
+			o.Config = cfg
+		})
+		if err != nil {
+			log.Printf("Warning: configuration watcher stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
 // generateRootAPIKey creates a new cryptographically secure root API key.
 // The key is prefixed with "orbitkey_root_" and uses URL-safe base64 encoding.
 func generateRootAPIKey() (string, error) {
@@ -171,5 +394,13 @@ func generateRootAPIKey() (string, error) {
 // This should be called when the application is shutting down to ensure all resources
 // are properly released.
 func (o *OrbitKeys) Close() {
+	if o.watchCancel != nil {
+		o.watchCancel()
+	}
+	if o.auditCloser != nil {
+		if err := o.auditCloser(); err != nil {
+			log.Printf("Warning: failed to close audit sink: %v", err)
+		}
+	}
 	database.CloseDB()
 } 
\ No newline at end of file
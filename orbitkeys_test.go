@@ -2,22 +2,20 @@ package orbitkeys
 
 import (
 	"testing"
-
-	"github.com/BasementPilot/orbit-keys/config"
 )
 
 func TestNew(t *testing.T) {
-	// Test with nil config (should load default)
-	t.Run("Nil config", func(t *testing.T) {
-		ok, err := New(nil)
+	// Test with no configuration set (should load defaults)
+	t.Run("Default config", func(t *testing.T) {
+		ok, err := New()
 		if err != nil {
-			t.Fatalf("New() with nil config failed: %v", err)
+			t.Fatalf("New() failed: %v", err)
 		}
 		if ok == nil {
 			t.Fatal("New() returned nil OrbitKeys")
 		}
 		if ok.Config == nil {
-			t.Fatal("Config is nil after initialization with nil config")
+			t.Fatal("Config is nil after initialization")
 		}
 		if ok.Config.RootAPIKey == "" {
 			t.Error("RootAPIKey is empty after initialization")
@@ -30,29 +28,28 @@ func TestNew(t *testing.T) {
 		}
 	})
 
-	// Test with custom config
+	// Test with configuration supplied via the environment, since New() no
+	// longer takes a *config.Config override directly
 	t.Run("Custom config", func(t *testing.T) {
-		cfg := &config.Config{
-			RootAPIKey: "orbitkey_test_root_key",
-			DBPath:     "test.db",
-			BaseURL:    "/custom",
-		}
+		t.Setenv("ORBITKEYS_ROOT_API_KEY", "orbitkey_test_root_key")
+		t.Setenv("ORBITKEYS_DB_PATH", "test.db")
+		t.Setenv("ORBITKEYS_BASE_URL", "/custom")
 
-		ok, err := New(cfg)
+		ok, err := New()
 		if err != nil {
 			t.Fatalf("New() with custom config failed: %v", err)
 		}
 		if ok == nil {
 			t.Fatal("New() returned nil OrbitKeys")
 		}
-		if ok.Config.RootAPIKey != cfg.RootAPIKey {
-			t.Errorf("Expected RootAPIKey '%s', got '%s'", cfg.RootAPIKey, ok.Config.RootAPIKey)
+		if ok.Config.RootAPIKey != "orbitkey_test_root_key" {
+			t.Errorf("Expected RootAPIKey 'orbitkey_test_root_key', got '%s'", ok.Config.RootAPIKey)
 		}
-		if ok.Config.DBPath != cfg.DBPath {
-			t.Errorf("Expected DBPath '%s', got '%s'", cfg.DBPath, ok.Config.DBPath)
+		if ok.Config.DBPath != "test.db" {
+			t.Errorf("Expected DBPath 'test.db', got '%s'", ok.Config.DBPath)
 		}
-		if ok.Config.BaseURL != cfg.BaseURL {
-			t.Errorf("Expected BaseURL '%s', got '%s'", cfg.BaseURL, ok.Config.BaseURL)
+		if ok.Config.BaseURL != "/custom" {
+			t.Errorf("Expected BaseURL '/custom', got '%s'", ok.Config.BaseURL)
 		}
 	})
 }
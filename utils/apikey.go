@@ -4,15 +4,19 @@
 package utils
 
 import (
-	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/BasementPilot/orbit-keys/internal/models"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // Security-related errors
@@ -39,21 +43,182 @@ const (
 	// MinTrimmedKeyLength is the minimum length a key should have after removing the prefix
 	// This ensures keys have sufficient entropy
 	MinTrimmedKeyLength = 22
+
+	// KeyLookupPrefixLength is how many characters of the trimmed key are
+	// stored, unhashed, as models.APIKey.KeyPrefix so APIKeyAuth can narrow
+	// a lookup to a handful of candidate rows before hashing and comparing.
+	KeyLookupPrefixLength = 12
+
+	// DefaultKeyHashAlgorithm is used when Config.KeyHashAlgorithm is empty
+	// or SetKeyHashConfig hasn't been called (e.g. in tests).
+	DefaultKeyHashAlgorithm = "sha256"
+)
+
+// keyPepper and keyHashAlgorithm are installed once at startup by
+// SetKeyHashConfig, mirroring how policy.SetEnforcer/audit.SetSinks install
+// their own subsystem state. They default to an empty pepper and SHA-256
+// so tests and tools that never call SetKeyHashConfig still get a usable,
+// if unpeppered, hash.
+var (
+	keyPepper        string
+	keyHashAlgorithm = DefaultKeyHashAlgorithm
 )
 
+// SetKeyHashConfig installs the per-install pepper mixed into every API key
+// hash and the algorithm ("sha256", "argon2id", or "bcrypt") used for new
+// hashes. Existing hashes keep verifying correctly regardless of algorithm
+// changes, since VerifyAPIKeyHash reads the algorithm back out of the stored
+// hash.
+func SetKeyHashConfig(pepper, algorithm string) {
+	keyPepper = pepper
+	if algorithm != "" {
+		keyHashAlgorithm = algorithm
+	}
+}
+
+// KeyLookupPrefix returns the non-secret prefix of key used to narrow a
+// KeyHash lookup, taken from the portion after KeyPrefix.
+func KeyLookupPrefix(key string) string {
+	trimmed := strings.TrimPrefix(key, KeyPrefix)
+	if len(trimmed) <= KeyLookupPrefixLength {
+		return trimmed
+	}
+	return trimmed[:KeyLookupPrefixLength]
+}
+
+// HashAPIKey hashes key with the pepper and algorithm installed by
+// SetKeyHashConfig. The returned string is "<algorithm>$<encoded hash>", so
+// VerifyAPIKeyHash can verify it later even if the configured algorithm has
+// since changed.
+func HashAPIKey(key string) (string, error) {
+	return hashAPIKeyWithAlgorithm(key, keyHashAlgorithm)
+}
+
+func hashAPIKeyWithAlgorithm(key, algorithm string) (string, error) {
+	switch algorithm {
+	case "argon2id":
+		// The pepper doubles as the salt: it's per-install and constant,
+		// which is acceptable here because the pepper - unlike a per-key
+		// salt - is never stored alongside the hash it protects.
+		salt := sha256.Sum256([]byte("orbitkeys-argon2id-salt:" + keyPepper))
+		hash := argon2.IDKey([]byte(key), salt[:], 1, 64*1024, 4, 32)
+		return "argon2id$" + base64.RawURLEncoding.EncodeToString(hash), nil
+	case "bcrypt":
+		// bcrypt silently truncates input past 72 bytes, so the peppered key
+		// is pre-hashed to a fixed-size digest before it's handed to bcrypt,
+		// same as the pepper-mixing done for the other algorithms.
+		sum := sha256.Sum256([]byte(keyPepper + key))
+		hash, err := bcrypt.GenerateFromPassword(sum[:], bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		return "bcrypt$" + base64.RawURLEncoding.EncodeToString(hash), nil
+	case "sha256", "":
+		sum := sha256.Sum256([]byte(keyPepper + key))
+		return "sha256$" + hex.EncodeToString(sum[:]), nil
+	default:
+		return "", fmt.Errorf("unsupported key hash algorithm %q", algorithm)
+	}
+}
+
+// VerifyAPIKeyHash reports whether key hashes to storedHash, using whichever
+// algorithm storedHash itself was produced with, and comparing in constant
+// time to avoid a timing side channel.
+func VerifyAPIKeyHash(key, storedHash string) bool {
+	algorithm, encoded, ok := strings.Cut(storedHash, "$")
+	if !ok {
+		return false
+	}
+
+	if algorithm == "bcrypt" {
+		// bcrypt hashes embed their own per-hash salt, so, unlike the other
+		// algorithms, a candidate can't be recomputed and compared directly;
+		// CompareHashAndPassword itself runs in constant time.
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return false
+		}
+		sum := sha256.Sum256([]byte(keyPepper + key))
+		return bcrypt.CompareHashAndPassword(decoded, sum[:]) == nil
+	}
+
+	candidate, err := hashAPIKeyWithAlgorithm(key, algorithm)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(candidate), []byte(storedHash)) == 1
+}
+
+// FindAPIKeyByPlaintext looks up the APIKey matching key without ever using
+// the plaintext key in a SQL WHERE clause: candidates are narrowed by the
+// non-secret KeyPrefix index, then each candidate's KeyHash is checked with
+// VerifyAPIKeyHash.
+//
+// A FormatJWT key is handled separately, by findJWTAPIKey: its signature and
+// expiry are verified directly, skipping the KeyHash candidate scan below.
+//
+// A recently-seen key is served out of an in-process LRU cache instead of
+// hitting the database again; see InvalidateAPIKeyCache for how that cache
+// is kept from serving a deleted or re-expired key past its short TTL.
+func FindAPIKeyByPlaintext(db *gorm.DB, key string) (*models.APIKey, error) {
+	if !ValidateAPIKey(key) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if looksLikeJWT(key) {
+		return findJWTAPIKey(db, key)
+	}
+
+	prefix := KeyLookupPrefix(key)
+
+	if cached, ok := apiKeyCache.get(prefix); ok && VerifyAPIKeyHash(key, cached.KeyHash) {
+		return &cached, nil
+	}
+
+	var candidates []models.APIKey
+	if err := db.Preload("Role").Where("key_prefix = ?", prefix).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		if VerifyAPIKeyHash(key, candidates[i].KeyHash) {
+			apiKeyCache.put(prefix, candidates[i])
+			return &candidates[i], nil
+		}
+	}
+
+	// Not a current key: check keys mid-rotation, whose still-valid previous
+	// hash lives under their previous prefix rather than prefix.
+	var rotating []models.APIKey
+	if err := db.Preload("Role").Where("previous_key_prefix = ? AND previous_key_expires_at > ?", prefix, time.Now()).Find(&rotating).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range rotating {
+		if VerifyAPIKeyHash(key, rotating[i].PreviousKeyHash) {
+			return &rotating[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
 // GenerateAPIKey creates a new cryptographically secure API key with the specified length.
-// The key is generated using secure random bytes and encoded using URL-safe base64.
+// Entropy and encoding come from the package-level KeyGenerator (see
+// SetKeyGenerator/SelectKeyGenerator), which defaults to crypto/rand
+// encoded as URL-safe base64.
 // If length is < MinKeyLength, DefaultKeyLength will be used instead.
 //
-// The generated key will be prefixed with KeyPrefix and have any trailing '=' characters removed.
-// Returns the generated key as a string and any error encountered during generation.
+// Returns the generated key, prefixed with KeyPrefix, and any error
+// encountered during generation.
 func GenerateAPIKey(length int) (string, error) {
 	if length < MinKeyLength {
 		length = DefaultKeyLength
 	}
 
-	bytes := make([]byte, length)
-	n, err := rand.Read(bytes)
+	raw := make([]byte, length)
+	n, err := keyGenerator.Read(raw)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", ErrKeyGeneration, err)
 	}
@@ -63,15 +228,13 @@ func GenerateAPIKey(length int) (string, error) {
 		return "", fmt.Errorf("%w: requested %d bytes but got %d", ErrInvalidKeyLength, length, n)
 	}
 
-	key := base64.URLEncoding.EncodeToString(bytes)
-	// Remove trailing = characters
-	key = strings.TrimRight(key, "=")
-
-	return KeyPrefix + key, nil
+	return KeyPrefix + keyGenerator.Encode(raw), nil
 }
 
 // ValidateAPIKey checks if a given string is a valid API key.
 // It verifies the key is not empty, starts with the correct prefix, and has appropriate length.
+// A FormatJWT key is validated by shape alone (looksLikeJWT); its signature
+// and expiry are checked separately, by parseJWTAPIKey.
 //
 // Returns true if the key is valid, false otherwise.
 func ValidateAPIKey(key string) bool {
@@ -80,6 +243,10 @@ func ValidateAPIKey(key string) bool {
 		return false
 	}
 
+	if looksLikeJWT(key) {
+		return true
+	}
+
 	// Check if key has the correct prefix
 	if !strings.HasPrefix(key, KeyPrefix) {
 		return false
@@ -112,42 +279,138 @@ func IsRootAPIKey(key, rootKey string) bool {
 //   - roleID: The ID of the role to associate with this key
 //   - description: A human-readable description of the key's purpose
 //   - customData: Optional JSON string for storing custom metadata like user IDs
+//   - scopes: Optional comma-separated permission list (same grammar as Role.Permissions)
+//     that narrows the key below its role's permissions; empty leaves it unrestricted
+//   - format: FormatOpaque (or "") for a random token, FormatJWT for a
+//     self-contained, statelessly-verifiable token. See FindAPIKeyByPlaintext.
 //   - expiresIn: Optional duration after which the key will expire (nil for no expiration)
 //
 // Returns the created APIKey model and any error encountered during creation.
-func CreateAPIKey(roleID uint, description string, customData string, expiresIn *time.Duration) (*models.APIKey, error) {
+func CreateAPIKey(roleID uint, description string, customData string, scopes string, format KeyFormat, expiresIn *time.Duration) (*models.APIKey, error) {
 	if roleID == 0 {
 		return nil, errors.New("role ID cannot be zero")
 	}
+	if format == "" {
+		format = FormatOpaque
+	}
+
+	var expiresAt *time.Time
+	if expiresIn != nil && *expiresIn > 0 {
+		// Cap maximum expiration time to reasonable limit (e.g., 10 years)
+		maxDuration := 10 * 365 * 24 * time.Hour // 10 years
+		duration := *expiresIn
+
+		if duration > maxDuration {
+			duration = maxDuration
+		}
+
+		at := time.Now().Add(duration)
+		expiresAt = &at
+	}
+
+	var key string
+	switch format {
+	case FormatJWT:
+		jwtKey, err := newJWTAPIKey(roleID, scopes, expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate JWT API key: %w", err)
+		}
+		key = jwtKey
+	case FormatOpaque:
+		opaqueKey, err := GenerateAPIKey(DefaultKeyLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate API key: %w", err)
+		}
+		key = opaqueKey
+	default:
+		return nil, fmt.Errorf("unsupported API key format %q", format)
+	}
 
-	// Generate new API key
-	key, err := GenerateAPIKey(DefaultKeyLength)
+	// KeyHash/KeyPrefix are computed from the token string regardless of
+	// format, so a FormatJWT key's row still satisfies the not-null/unique
+	// constraint and remains listable and deletable via the admin API, even
+	// though authentication itself bypasses them for FormatJWT keys.
+	hash, err := HashAPIKey(key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate API key: %w", err)
+		return nil, fmt.Errorf("failed to hash API key: %w", err)
 	}
 
-	// Create new API key record
 	apiKey := &models.APIKey{
 		Key:         key,
+		KeyHash:     hash,
+		KeyPrefix:   KeyLookupPrefix(key),
 		RoleID:      roleID,
 		Description: description,
 		CustomData:  customData,
+		Scopes:      scopes,
+		Format:      string(format),
 		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
 	}
 
-	// Set expiration if provided
-	if expiresIn != nil && *expiresIn > 0 {
-		// Cap maximum expiration time to reasonable limit (e.g., 10 years)
-		maxDuration := 10 * 365 * 24 * time.Hour // 10 years
-		duration := *expiresIn
+	return apiKey, nil
+}
 
-		if duration > maxDuration {
-			duration = maxDuration
-		}
+// RotateAPIKey generates a new secret for apiKey while keeping its ID,
+// RoleID, Description, CreatedAt, and ExpiresAt unchanged, persists the
+// change, and invalidates both the old and new prefix from the lookup
+// cache. apiKey.Key holds the new plaintext on return, exactly once.
+//
+// If graceDuration > 0, the old key's hash is preserved as PreviousKeyHash
+// and stays valid, via FindAPIKeyByPlaintext, until graceDuration elapses,
+// letting callers roll keys without a coordinated cutover. A zero
+// graceDuration cuts the old key off immediately.
+func RotateAPIKey(db *gorm.DB, apiKey *models.APIKey, graceDuration time.Duration) error {
+	newKey, err := GenerateAPIKey(DefaultKeyLength)
+	if err != nil {
+		return fmt.Errorf("failed to generate API key: %w", err)
+	}
+	newHash, err := HashAPIKey(newKey)
+	if err != nil {
+		return fmt.Errorf("failed to hash API key: %w", err)
+	}
+
+	oldHash, oldPrefix := apiKey.KeyHash, apiKey.KeyPrefix
 
-		expiresAt := time.Now().Add(duration)
-		apiKey.ExpiresAt = &expiresAt
+	apiKey.Key = newKey
+	apiKey.KeyHash = newHash
+	apiKey.KeyPrefix = KeyLookupPrefix(newKey)
+
+	if graceDuration > 0 {
+		graceExpiresAt := time.Now().Add(graceDuration)
+		apiKey.PreviousKeyHash = oldHash
+		apiKey.PreviousKeyPrefix = oldPrefix
+		apiKey.PreviousKeyExpiresAt = &graceExpiresAt
+	} else {
+		apiKey.PreviousKeyHash = ""
+		apiKey.PreviousKeyPrefix = ""
+		apiKey.PreviousKeyExpiresAt = nil
 	}
 
-	return apiKey, nil
+	if err := db.Save(apiKey).Error; err != nil {
+		return fmt.Errorf("failed to save rotated API key: %w", err)
+	}
+
+	InvalidateAPIKeyCache(oldPrefix)
+	InvalidateAPIKeyCache(apiKey.KeyPrefix)
+
+	return nil
+}
+
+// RevokeAPIKey immediately invalidates apiKey by setting its ExpiresAt to
+// now, persists the change, and invalidates it from the lookup cache.
+// Unlike deleting the row, this preserves the key for audit history; it's
+// treated identically to a naturally expired key by IsExpired and
+// FindAPIKeyByPlaintext.
+func RevokeAPIKey(db *gorm.DB, apiKey *models.APIKey) error {
+	now := time.Now()
+	apiKey.ExpiresAt = &now
+
+	if err := db.Save(apiKey).Error; err != nil {
+		return fmt.Errorf("failed to save revoked API key: %w", err)
+	}
+
+	InvalidateAPIKeyCache(apiKey.KeyPrefix)
+
+	return nil
 }
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/BasementPilot/orbit-keys/internal/models"
+)
+
+// apiKeyCacheCapacity and apiKeyCacheTTL bound the in-process cache
+// FindAPIKeyByPlaintext consults before hitting the database. A short TTL
+// keeps a revoked or expired key from being served out of cache for long,
+// while InvalidateAPIKeyCache lets callers evict an entry immediately
+// instead of waiting it out.
+const (
+	apiKeyCacheCapacity = 1024
+	apiKeyCacheTTL      = 1 * time.Minute
+)
+
+// apiKeyCache is the process-wide cache used by FindAPIKeyByPlaintext to
+// avoid a database round trip on every request's hot path.
+var apiKeyCache = newAPIKeyLRUCache(apiKeyCacheCapacity, apiKeyCacheTTL)
+
+// apiKeyLRUCache is a small, mutex-protected, TTL-evicting LRU cache keyed
+// by KeyPrefix. It is not a general-purpose cache: it exists solely to
+// avoid a "SELECT ... WHERE key_prefix = ?" round trip for keys that were
+// recently looked up.
+type apiKeyLRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type apiKeyCacheEntry struct {
+	prefix    string
+	key       models.APIKey
+	expiresAt time.Time
+}
+
+func newAPIKeyLRUCache(capacity int, ttl time.Duration) *apiKeyLRUCache {
+	return &apiKeyLRUCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached APIKey for prefix, if present and not yet expired.
+// A hit moves the entry to the front of the eviction order.
+func (c *apiKeyLRUCache) get(prefix string) (models.APIKey, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[prefix]
+	if !ok {
+		return models.APIKey{}, false
+	}
+
+	entry := el.Value.(*apiKeyCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, prefix)
+		return models.APIKey{}, false
+	}
+
+	c.order.MoveToFront(el)
+	return entry.key, true
+}
+
+// put inserts or refreshes prefix's cached entry, evicting the
+// least-recently-used entry if the cache is at capacity.
+func (c *apiKeyLRUCache) put(prefix string, key models.APIKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[prefix]; ok {
+		el.Value.(*apiKeyCacheEntry).key = key
+		el.Value.(*apiKeyCacheEntry).expiresAt = time.Now().Add(c.ttl)
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&apiKeyCacheEntry{
+		prefix:    prefix,
+		key:       key,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.items[prefix] = el
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*apiKeyCacheEntry).prefix)
+		}
+	}
+}
+
+// invalidate evicts prefix's cached entry, if any.
+func (c *apiKeyLRUCache) invalidate(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[prefix]; ok {
+		c.order.Remove(el)
+		delete(c.items, prefix)
+	}
+}
+
+// InvalidateAPIKeyCache evicts prefix's cached entry from
+// FindAPIKeyByPlaintext's in-process cache, if present. Callers that delete
+// an API key or change its expiration should call this with the key's
+// KeyPrefix afterward, so the hot path can't keep serving stale data until
+// the TTL naturally expires.
+func InvalidateAPIKeyCache(prefix string) {
+	apiKeyCache.invalidate(prefix)
+}
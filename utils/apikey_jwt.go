@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BasementPilot/orbit-keys/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// KeyFormat selects the shape an API key is minted in.
+type KeyFormat string
+
+const (
+	// FormatOpaque is the default: a random token looked up via KeyHash.
+	FormatOpaque KeyFormat = "opaque"
+
+	// FormatJWT mints a self-contained, HS256-signed token that
+	// FindAPIKeyByPlaintext can verify without a KeyHash candidate scan.
+	FormatJWT KeyFormat = "jwt"
+)
+
+// apiKeyJWTSigningKey is the HMAC secret used to sign and verify FormatJWT
+// keys, installed once at startup by SetAPIKeyJWTSigningKey, mirroring how
+// SetKeyHashConfig installs keyPepper/keyHashAlgorithm. Left empty, FormatJWT
+// keys can't be minted or verified.
+var apiKeyJWTSigningKey []byte
+
+// SetAPIKeyJWTSigningKey installs the HMAC secret used to sign and verify
+// FormatJWT API keys.
+func SetAPIKeyJWTSigningKey(key []byte) {
+	apiKeyJWTSigningKey = key
+}
+
+// jwtAPIKeyClaims are embedded in a FormatJWT key's payload. They carry
+// enough information for FindAPIKeyByPlaintext to authenticate the key and
+// hydrate its Role without a KeyHash lookup.
+type jwtAPIKeyClaims struct {
+	RoleID uint   `json:"role_id"`
+	Scopes string `json:"scopes,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// looksLikeJWT reports whether key has the three dot-separated segments of a
+// JWT, distinguishing a FormatJWT key from an opaque, KeyPrefix-bearing one
+// without a DB lookup.
+func looksLikeJWT(key string) bool {
+	return strings.Count(key, ".") == 2
+}
+
+// newJWTAPIKey mints a FormatJWT key for roleID/scopes, signed with
+// apiKeyJWTSigningKey and valid until expiresAt (nil for no expiration).
+func newJWTAPIKey(roleID uint, scopes string, expiresAt *time.Time) (string, error) {
+	if len(apiKeyJWTSigningKey) == 0 {
+		return "", errors.New("API key JWT signing key is not configured")
+	}
+
+	claims := jwtAPIKeyClaims{
+		RoleID: roleID,
+		Scopes: scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if expiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*expiresAt)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(apiKeyJWTSigningKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT API key: %w", err)
+	}
+
+	return signed, nil
+}
+
+// parseJWTAPIKey verifies key's HS256 signature and expiry against
+// apiKeyJWTSigningKey and returns its claims.
+func parseJWTAPIKey(key string) (*jwtAPIKeyClaims, error) {
+	if len(apiKeyJWTSigningKey) == 0 {
+		return nil, errors.New("API key JWT signing key is not configured")
+	}
+
+	claims := &jwtAPIKeyClaims{}
+	_, err := jwt.ParseWithClaims(key, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Method)
+		}
+		return apiKeyJWTSigningKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT API key: %w", err)
+	}
+
+	return claims, nil
+}
+
+// findJWTAPIKey verifies key as a FormatJWT token and hydrates a synthetic
+// APIKey from its claims, loading only the Role it names (a single
+// primary-key lookup) rather than scanning KeyHash candidates. The returned
+// APIKey is not backed by a specific row: revoking or deleting the DB row
+// created alongside this key at issuance does not invalidate it before its
+// own exp claim elapses, since the signature alone proves validity.
+func findJWTAPIKey(db *gorm.DB, key string) (*models.APIKey, error) {
+	claims, err := parseJWTAPIKey(key)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var role models.Role
+	if err := db.First(&role, claims.RoleID).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.APIKey{
+		RoleID: claims.RoleID,
+		Role:   role,
+		Scopes: claims.Scopes,
+		Format: string(FormatJWT),
+	}, nil
+}
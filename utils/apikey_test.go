@@ -189,7 +189,7 @@ func TestCreateAPIKey(t *testing.T) {
 		customData := "{\"user_id\": 123, \"username\": \"testuser\"}"
 		duration := 24 * time.Hour
 
-		apiKey, err := CreateAPIKey(roleID, description, customData, &duration)
+		apiKey, err := CreateAPIKey(roleID, description, customData, "", FormatOpaque, &duration)
 		if err != nil {
 			t.Fatalf("CreateAPIKey failed with error: %v", err)
 		}
@@ -231,7 +231,7 @@ func TestCreateAPIKey(t *testing.T) {
 		customData := ""
 		duration := 24 * time.Hour
 
-		_, err := CreateAPIKey(roleID, description, customData, &duration)
+		_, err := CreateAPIKey(roleID, description, customData, "", FormatOpaque, &duration)
 		if err == nil {
 			t.Error("Expected error for role ID 0")
 		}
@@ -243,7 +243,7 @@ func TestCreateAPIKey(t *testing.T) {
 		description := "Test API Key without expiration"
 		customData := "{\"user_id\": 456}"
 
-		apiKey, err := CreateAPIKey(roleID, description, customData, nil)
+		apiKey, err := CreateAPIKey(roleID, description, customData, "", FormatOpaque, nil)
 		if err != nil {
 			t.Fatalf("CreateAPIKey failed with error: %v", err)
 		}
@@ -264,7 +264,7 @@ func TestCreateAPIKey(t *testing.T) {
 		customData := ""
 		duration := 24 * time.Hour
 
-		apiKey, err := CreateAPIKey(roleID, description, customData, &duration)
+		apiKey, err := CreateAPIKey(roleID, description, customData, "", FormatOpaque, &duration)
 		if err != nil {
 			t.Fatalf("CreateAPIKey failed with error: %v", err)
 		}
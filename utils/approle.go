@@ -0,0 +1,60 @@
+// Package utils provides utility functions for working with API keys in the OrbitKeys system.
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RoleIDPrefix is the string prefix added to generated AppRole role_id values.
+	RoleIDPrefix = "approle_"
+
+	// SecretIDPrefix is the string prefix added to generated AppRole secret_id values.
+	SecretIDPrefix = "secret_"
+)
+
+// GenerateRoleID creates a new, non-secret identifier for an AppRole.
+// Unlike a SecretID, the role_id is safe to log or embed in client configuration.
+func GenerateRoleID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrKeyGeneration, err)
+	}
+
+	return RoleIDPrefix + strings.TrimRight(base64.URLEncoding.EncodeToString(bytes), "="), nil
+}
+
+// GenerateSecretID creates a new cryptographically secure SecretID.
+// The plaintext value is returned to the caller exactly once; only its hash
+// (see HashSecretID) should ever be persisted.
+func GenerateSecretID() (string, error) {
+	bytes := make([]byte, DefaultKeyLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrKeyGeneration, err)
+	}
+
+	return SecretIDPrefix + strings.TrimRight(base64.URLEncoding.EncodeToString(bytes), "="), nil
+}
+
+// HashSecretID computes the SHA-256 hash of a plaintext SecretID for storage.
+// The hash is returned as a hex-encoded string.
+func HashSecretID(secretID string) string {
+	sum := sha256.Sum256([]byte(secretID))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySecretID checks a plaintext SecretID against a stored hash using a
+// constant-time comparison to prevent timing attacks.
+func VerifySecretID(secretID, hash string) bool {
+	if secretID == "" || hash == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(HashSecretID(secretID)), []byte(hash)) == 1
+}